@@ -0,0 +1,142 @@
+package serial_lcd
+
+import (
+	"io"
+	"sync"
+)
+
+// OverflowPolicy decides what an AsyncLCD does with writes that don't fit in
+// its queue.
+type OverflowPolicy int
+
+const (
+	// Coalesce holds writes that don't fit in the queue and combines them into
+	// a single write that is queued as soon as there's room.  Nothing is lost,
+	// but the held writes can grow without bound if the display can't keep up.
+	Coalesce OverflowPolicy = iota
+	// Drop discards writes that don't fit in the queue.
+	Drop
+)
+
+// AsyncLCD is an LCD whose writes are queued and sent by a background
+// goroutine, so that a slow serial port doesn't hold up the caller.  Errors
+// from the background writes are returned by the next write.  Close sends
+// everything still queued before closing the underlying connection, and like
+// closing an LCD, closing it again returns ErrClosed.
+type AsyncLCD struct {
+	LCD
+	q *asyncQueue
+}
+
+// Async returns an AsyncLCD that queues up to queueSize writes to l.  The
+// queue holds at least one write.
+func (l LCD) Async(queueSize int) *AsyncLCD {
+	q := &asyncQueue{
+		ReadWriteCloser: l.ReadWriteCloser,
+		ch:              make(chan []byte, max(queueSize, 1)),
+		stopped:         make(chan struct{}),
+	}
+	q.idle = sync.NewCond(&q.mu)
+	go q.run()
+	return &AsyncLCD{LCD{q, l.state(), l.vp}, q}
+}
+
+// SetOverflowPolicy sets what happens to writes when the queue is full.  The
+// default is Coalesce.
+func (a *AsyncLCD) SetOverflowPolicy(p OverflowPolicy) {
+	a.q.mu.Lock()
+	a.q.policy = p
+	a.q.mu.Unlock()
+}
+
+type asyncQueue struct {
+	io.ReadWriteCloser
+	ch      chan []byte
+	stopped chan struct{} // closed when run returns
+
+	mu       sync.Mutex
+	idle     *sync.Cond // signaled when nothing is pending
+	policy   OverflowPolicy
+	overflow []byte // coalesced writes waiting for room in ch
+	pending  int    // writes in ch or being written
+	err      error  // error from a background write
+	closed   bool
+}
+
+func (q *asyncQueue) Write(p []byte) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, ErrClosed
+	}
+	err := q.err
+	q.err = nil
+	if q.overflow != nil {
+		q.overflow = append(q.overflow, p...)
+		return len(p), err
+	}
+	buf := append([]byte(nil), p...)
+	select {
+	case q.ch <- buf:
+		q.pending++
+	default:
+		if q.policy == Coalesce {
+			q.overflow = buf
+		}
+	}
+	return len(p), err
+}
+
+func (q *asyncQueue) run() {
+	defer close(q.stopped)
+	for buf := range q.ch {
+		_, err := q.ReadWriteCloser.Write(buf)
+		q.mu.Lock()
+		if err != nil {
+			q.err = err
+		}
+		q.pending--
+		if q.overflow != nil {
+			select {
+			case q.ch <- q.overflow:
+				q.overflow = nil
+				q.pending++
+			default:
+			}
+		}
+		if q.pending == 0 && q.overflow == nil {
+			q.idle.Broadcast()
+		}
+		q.mu.Unlock()
+	}
+}
+
+// Drain waits until everything queued has been written.
+func (q *asyncQueue) Drain() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.waitIdleLocked()
+	err := q.err
+	q.err = nil
+	return err
+}
+
+func (q *asyncQueue) waitIdleLocked() {
+	for q.pending > 0 || q.overflow != nil {
+		q.idle.Wait()
+	}
+}
+
+func (q *asyncQueue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrClosed
+	}
+	q.closed = true
+	q.waitIdleLocked()
+	close(q.ch)
+	q.mu.Unlock()
+	<-q.stopped
+	return q.ReadWriteCloser.Close()
+}
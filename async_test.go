@@ -0,0 +1,26 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAsyncLCD_Close(t *testing.T) {
+	lcd, port := newMemLCD()
+	a := lcd.Async(4)
+	if err := a.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := port.Bytes(), cmd(CLEAR); !bytes.Equal(got, want) {
+		t.Errorf("Close sent %q, want the queued %q", got, want)
+	}
+	if err := a.Close(); err != ErrClosed {
+		t.Errorf("second Close returned %v, want ErrClosed", err)
+	}
+	if err := a.Clear(); err != ErrClosed {
+		t.Errorf("Clear after Close returned %v, want ErrClosed", err)
+	}
+}
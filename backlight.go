@@ -0,0 +1,175 @@
+package serial_lcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Dim lowers the backlight brightness by percent (0-100) for duration and then
+// restores it, e.g. to draw attention to a notification.  Both changes are
+// saved to the backpack's EEPROM, see SetBrightness, so this is meant for the
+// occasional notification: dimming once a minute wears the EEPROM out in about
+// a month.  Flash doesn't have that cost.
+func (l LCD) Dim(percent float64, duration time.Duration) error {
+	return l.DimContext(context.Background(), percent, duration)
+}
+
+// DimContext is like Dim but stops early if ctx is cancelled, restoring the
+// brightness and returning ctx.Err().
+func (l LCD) DimContext(ctx context.Context, percent float64, duration time.Duration) error {
+	orig := l.Brightness()
+	frac := 1 - math.Max(0, math.Min(percent, 100))/100
+	if err := l.SetBrightness(clampByte(float64(orig) * frac)); err != nil {
+		return err
+	}
+	err := sleep(ctx, duration)
+	if e := l.SetBrightness(orig); err == nil {
+		err = e
+	}
+	return err
+}
+
+// Flash turns the backlight off and back on the given number of times, taking
+// period for each flash.
+func (l LCD) Flash(times int, period time.Duration) error {
+	return l.FlashContext(context.Background(), times, period)
+}
+
+// FlashContext is like Flash but stops early if ctx is cancelled, turning the
+// backlight back on and returning ctx.Err().
+func (l LCD) FlashContext(ctx context.Context, times int, period time.Duration) error {
+	for i := 0; i < times; i++ {
+		if err := l.Off(); err != nil {
+			return err
+		}
+		err := sleep(ctx, period/2)
+		if e := l.On(); err == nil {
+			err = e
+		}
+		if err == nil {
+			err = sleep(ctx, period-period/2)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MinAnimationInterval is the shortest interval between colors allowed by
+// AnimateBackground.
+var MinAnimationInterval = 10 * time.Second
+
+// AnimateBackground cycles the backlight through colors, changing to the next
+// one every interval, until ctx is cancelled, and then returns ctx.Err().
+//
+// Each change is saved to the backpack's EEPROM, see SetBG, which wears out
+// after around 100,000 writes, so this is only suitable for occasional use such
+// as showing a status.  Intervals shorter than MinAnimationInterval are raised
+// to it, and even then continuous animation wears the EEPROM out in about 12
+// days; at one change a minute it lasts about 70 days.
+func (l LCD) AnimateBackground(ctx context.Context, colors []Color, interval time.Duration) error {
+	if len(colors) == 0 {
+		return errors.New("serial_lcd: no colors to animate")
+	}
+	ticker := time.NewTicker(max(interval, MinAnimationInterval))
+	defer ticker.Stop()
+	for i := 0; ; i = (i + 1) % len(colors) {
+		c := colors[i]
+		if err := l.SetBG(c.R, c.G, c.B); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// sleep waits for d, returning early with ctx.Err() if ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// BacklightMode is a backlight behavior set by SetBacklightMode.
+type BacklightMode uint8
+
+const (
+	BacklightSteadyOn BacklightMode = iota
+	BacklightOff
+	BacklightBlinking
+)
+
+// SetBacklightMode turns the backlight on, off, or makes it blink at the rate
+// set by SetBlinkRate (1 Hz by default).  The firmware has no blink command, so
+// blinking is done by a background goroutine that runs until the mode is
+// changed again.
+func (l LCD) SetBacklightMode(m BacklightMode) error {
+	l.stopBlinking()
+	switch m {
+	case BacklightSteadyOn:
+		return l.On()
+	case BacklightOff:
+		return l.Off()
+	case BacklightBlinking:
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		l.state().mu.Lock()
+		l.state().stopBlink, l.state().blinkDone = cancel, done
+		l.state().mu.Unlock()
+		go l.blink(ctx, done)
+		return nil
+	}
+	return fmt.Errorf("serial_lcd: unknown backlight mode %d", m)
+}
+
+// SetBlinkRate sets how many times per second the backlight blinks in
+// BacklightBlinking mode.  A new rate takes effect on the next blink.
+func (l LCD) SetBlinkRate(hz float64) error {
+	if !(hz > 0) {
+		return fmt.Errorf("serial_lcd: invalid blink rate %v", hz)
+	}
+	l.state().mu.Lock()
+	l.state().blinkHz = hz
+	l.state().mu.Unlock()
+	return nil
+}
+
+func (l LCD) blink(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	for {
+		l.state().mu.Lock()
+		half := time.Duration(float64(time.Second) / l.state().blinkHz / 2)
+		l.state().mu.Unlock()
+		if err := l.FlashContext(ctx, 1, 2*half); ctx.Err() != nil || errors.Is(err, ErrClosed) {
+			return
+		} else if err != nil {
+			l.logf("serial_lcd: blinking backlight: %v", err)
+			sleep(ctx, 2*half)
+		}
+	}
+}
+
+// stopBlinking stops BacklightBlinking mode, waiting for the blinking to end
+// so that it doesn't undo what the caller sends next.
+func (l LCD) stopBlinking() {
+	l.state().mu.Lock()
+	stop, done := l.state().stopBlink, l.state().blinkDone
+	l.state().stopBlink, l.state().blinkDone = nil, nil
+	l.state().mu.Unlock()
+	if stop != nil {
+		stop()
+		<-done
+	}
+}
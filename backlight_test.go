@@ -0,0 +1,61 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAnimateBackground(t *testing.T) {
+	lcd, port := newMemLCD()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	colors := []Color{{1, 2, 3}, {4, 5, 6}}
+	if err := lcd.AnimateBackground(ctx, colors, time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("AnimateBackground returned %v when cancelled, want context.DeadlineExceeded", err)
+	}
+	// The interval is raised to MinAnimationInterval, so only the first color
+	// is shown in time.
+	if got, want := port.Bytes(), cmd(SET_RGB_BACKLIGHT_COLOR, 1, 2, 3); !bytes.Equal(got, want) {
+		t.Errorf("AnimateBackground sent %q, want %q", got, want)
+	}
+}
+
+// brightnessWrites counts the brightness commands in sent.
+func brightnessWrites(sent []byte) int {
+	return bytes.Count(sent, cmd(BRIGHTNESS))
+}
+
+func TestSetBrightnessSmooth_LimitsWrites(t *testing.T) {
+	lcd, port := newMemLCD()
+	lcd.SetBrightness(0)
+	port.Reset()
+	if err := lcd.SetBrightnessSmooth(255, 200*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if n := brightnessWrites(port.Bytes()); n > fadeSteps {
+		t.Errorf("fade sent %d brightness changes, want at most %d", n, fadeSteps)
+	}
+	if b := lcd.Brightness(); b != 255 {
+		t.Errorf("brightness after the fade is %d, want 255", b)
+	}
+}
+
+func TestAutoBrightness_IgnoresSmallChanges(t *testing.T) {
+	lcd, port := newMemLCD()
+	for _, lux := range []float64{500, 501, 499, 503, 498} {
+		if err := lcd.AutoBrightness(lux, 0, 1000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := brightnessWrites(port.Bytes()); n != 1 {
+		t.Errorf("noisy readings sent %d brightness changes, want 1", n)
+	}
+	if err := lcd.AutoBrightness(0, 0, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if b := lcd.Brightness(); b != 0 {
+		t.Errorf("brightness in the dark is %d, want 0", b)
+	}
+}
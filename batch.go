@@ -0,0 +1,123 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// Batch calls fn with an LCD that collects everything written to it instead of
+// sending it, then sends all of it to the display at once.  Nothing is sent if
+// fn returns an error.  The LCD's tracked state, such as the cursor position,
+// is updated as fn runs, and the changes are undone if fn or the send fails.
+// Settings that other goroutines changed in the meantime are kept.  A pending
+// clear from WithClearOnOpen is also kept, and sent with the next write.
+//
+// Commands that the backpack needs time to process, such as CreateCustomChar,
+// split the batch: everything up to and including the command is sent, then
+// the rest is sent after the command's delay.  This makes loading a full set
+// of custom characters in one batch both fast and reliable.
+func (l LCD) Batch(fn func(LCD) error) (err error) {
+	l.state().mu.Lock()
+	before, clear := l.state().tracked, l.state().pendingClear
+	l.state().mu.Unlock()
+	var after tracked // the state once fn has run
+	defer func() {
+		if err != nil {
+			l.state().mu.Lock()
+			l.state().tracked.revert(before, after)
+			l.state().pendingClear = l.state().pendingClear || clear
+			l.state().mu.Unlock()
+		}
+	}()
+	b := &batch{ReadWriteCloser: l.ReadWriteCloser, quirks: l.quirks()}
+	err = fn(LCD{b, l.state(), l.vp})
+	l.state().mu.Lock()
+	after = l.state().tracked
+	l.state().mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return l.exclusive(func(l LCD) error {
+		start := 0
+		for _, p := range b.pauses {
+			if err := dropN(l.send(b.buf.Bytes()[start:p.at])); err != nil {
+				return err
+			}
+			time.Sleep(p.delay)
+			start = p.at
+		}
+		if start == b.buf.Len() {
+			return nil
+		}
+		return dropN(l.send(b.buf.Bytes()[start:]))
+	})
+}
+
+// revert undoes a batch's changes to t, which went from before to after.
+// Fields that have been changed again since are left alone.
+func (t *tracked) revert(before, after tracked) {
+	revertField(&t.brightness, before.brightness, after.brightness)
+	revertField(&t.contrast, before.contrast, after.contrast)
+	revertField(&t.inverted, before.inverted, after.inverted)
+	revertField(&t.autoscroll, before.autoscroll, after.autoscroll)
+	revertField(&t.cols, before.cols, after.cols)
+	revertField(&t.rows, before.rows, after.rows)
+	revertField(&t.col, before.col, after.col)
+	revertField(&t.row, before.row, after.row)
+	revertField(&t.bg, before.bg, after.bg)
+	revertField(&t.off, before.off, after.off)
+	revertField(&t.bank, before.bank, after.bank)
+	revertField(&t.chars, before.chars, after.chars)
+}
+
+// revertField sets *v back to before if it's still after.
+func revertField[T comparable](v *T, before, after T) {
+	if *v == after {
+		*v = before
+	}
+}
+
+// batch buffers writes until the end of a Batch call.
+type batch struct {
+	io.ReadWriteCloser
+	buf    bytes.Buffer
+	pauses []pause
+	quirks FirmwareQuirks // for finding where each command ends
+}
+
+// pause is a delay needed after the first at bytes of a batch are sent.
+type pause struct {
+	at    int
+	delay time.Duration
+}
+
+// Write buffers p and notes a pause after each command in it that needs one.
+func (b *batch) Write(p []byte) (int, error) {
+	start := b.buf.Len()
+	n, err := b.buf.Write(p)
+	for i := 0; i < len(p); {
+		if p[i] != COMMAND {
+			i++
+			continue
+		}
+		end := i + commandLen(p[i:], b.quirks)
+		if i+1 < len(p) {
+			if d := commands[p[i+1]].delay; d > 0 {
+				b.pause(start+end, d)
+			}
+		}
+		i = end
+	}
+	return n, err
+}
+
+// pause adds a delay after the first at bytes, merging it with a pause that's
+// already there.
+func (b *batch) pause(at int, d time.Duration) {
+	if last := len(b.pauses) - 1; last >= 0 && b.pauses[last].at == at {
+		b.pauses[last].delay += d
+	} else {
+		b.pauses = append(b.pauses, pause{at, d})
+	}
+}
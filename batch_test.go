@@ -0,0 +1,171 @@
+package serial_lcd
+
+import (
+	"errors"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestBatch_RollsBackOnError(t *testing.T) {
+	orig := MinBGInterval
+	MinBGInterval = 0
+	defer func() { MinBGInterval = orig }()
+
+	changeEverything := func(l LCD) error {
+		l.MoveTo(5, 2)
+		l.SetBG(1, 2, 3)
+		l.SetBrightness(10)
+		_, err := l.RegisterChar("dot", Char{4})
+		return err
+	}
+	check := func(lcd LCD, what string) {
+		t.Helper()
+		if col, row := lcd.CursorPos(); col != 1 || row != 1 {
+			t.Errorf("cursor after %s is %d,%d, want 1,1", what, col, row)
+		}
+		if c := lcd.Snapshot(); c.BG != (Color{255, 255, 255}) || c.Brightness != 255 {
+			t.Errorf("settings after %s are %+v, want the defaults", what, c)
+		}
+		if _, err := lcd.CharSlot("dot"); err == nil {
+			t.Errorf("character registered in %s is still registered", what)
+		}
+	}
+
+	lcd, port := newMemLCD()
+	failed := errors.New("failed")
+	err := lcd.Batch(func(l LCD) error {
+		changeEverything(l)
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("Batch returned %v, want %v", err, failed)
+	}
+	if sent := port.Bytes(); len(sent) != 0 {
+		t.Errorf("failed batch sent %q", sent)
+	}
+	check(lcd, "a failed batch")
+
+	unplugged := New(&failingPort{err: syscall.EIO})
+	if err := unplugged.Batch(changeEverything); err == nil {
+		t.Fatal("Batch on an unplugged LCD succeeded")
+	}
+	check(unplugged, "a failed send")
+}
+
+// gatedPort is a serial port whose writes wait for gate to be closed and then
+// fail, announcing on writing that they've started.
+type gatedPort struct {
+	memPort
+	writing chan struct{}
+	gate    chan struct{}
+}
+
+func (p *gatedPort) Write([]byte) (int, error) {
+	p.writing <- struct{}{}
+	<-p.gate
+	return 0, syscall.EIO
+}
+
+func TestBatch_RollbackKeepsConcurrentChanges(t *testing.T) {
+	port := &gatedPort{writing: make(chan struct{}), gate: make(chan struct{})}
+	lcd := New(port)
+	done := make(chan error)
+	go func() {
+		done <- lcd.Batch(func(l LCD) error {
+			l.SetBrightness(10)
+			return l.SetContrast(20)
+		})
+	}()
+	<-port.writing
+	// Another goroutine changes the contrast and backlight while the batch is
+	// being sent.
+	lcd.track(nil, func(s *state) { s.contrast, s.off = 30, true })
+	close(port.gate)
+	if err := <-done; err == nil {
+		t.Fatal("Batch on an unplugged LCD succeeded")
+	}
+
+	s := lcd.Snapshot()
+	if s.Brightness != 255 {
+		t.Errorf("brightness after a failed batch is %d, want it rolled back to 255", s.Brightness)
+	}
+	if s.Contrast != 30 || !s.Off {
+		t.Errorf("settings after a failed batch are %+v, want the concurrent changes kept", s)
+	}
+}
+
+func TestBatch_PausesAfterEveryCommand(t *testing.T) {
+	b := &batch{ReadWriteCloser: &memPort{}}
+	b.Write([]byte("hi"))
+	b.Write(cat(cmd(SET_CURSOR_POSITION, 1, 1), cmd(CLEAR), cmd(SET_CURSOR_POSITION, 1, 2), cmd(BRIGHTNESS, 9), cmd(CONTRAST, 9)))
+	want := []pause{
+		{2 + 4 + 2, commands[CLEAR].delay},
+		{2 + 4 + 2 + 4 + 3, commands[BRIGHTNESS].delay},
+		{2 + 4 + 2 + 4 + 3 + 3, commands[CONTRAST].delay},
+	}
+	if !reflect.DeepEqual(b.pauses, want) {
+		t.Errorf("pauses = %v, want %v", b.pauses, want)
+	}
+
+	// A command's arguments aren't mistaken for commands.
+	b = &batch{ReadWriteCloser: &memPort{}}
+	b.Write(cmd(SET_CURSOR_POSITION, COMMAND, CLEAR))
+	if len(b.pauses) != 0 {
+		t.Errorf("pauses = %v for arguments that look like a command", b.pauses)
+	}
+}
+
+func TestBatch_SendsAfterPause(t *testing.T) {
+	lcd, port := newMemLCD()
+	start := time.Now()
+	err := lcd.Batch(func(l LCD) error {
+		return l.Raw(cat(cmd(BRIGHTNESS, 1), cmd(CONTRAST, 2))...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, want := time.Since(start), commands[BRIGHTNESS].delay+commands[CONTRAST].delay; d < want {
+		t.Errorf("batch took %v, want at least %v for the pauses", d, want)
+	}
+	if got, want := port.Bytes(), cat(cmd(BRIGHTNESS, 1), cmd(CONTRAST, 2)); string(got) != string(want) {
+		t.Errorf("batch sent %q, want %q", got, want)
+	}
+}
+
+func TestBatch_KeepsPendingClearOnError(t *testing.T) {
+	driver, port := newPipePair(t)
+	fakeOpen(t, port)
+	w := listen(t, driver)
+	lcd, err := Open("/dev/ttyUSB0", 9600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := errors.New("failed")
+	lcd.Batch(func(l LCD) error {
+		l.Raw('x')
+		return failed
+	})
+	w.expectNothing()
+	lcd.Raw('y')
+	w.expect(cat(cmd(CLEAR), []byte("y"))...)
+}
+
+func TestBatch_KeepsPendingClearOnSendError(t *testing.T) {
+	p := &failingPort{err: syscall.EIO}
+	fakeOpen(t, p)
+	lcd, err := Open("/dev/ttyUSB0", 9600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lcd.Batch(func(l LCD) error { return l.Raw('x') }); err == nil {
+		t.Fatal("Batch on an unplugged LCD succeeded")
+	}
+	lcd.state().mu.Lock()
+	pending := lcd.state().pendingClear
+	lcd.state().mu.Unlock()
+	if !pending {
+		t.Error("the pending clear was lost when the batch couldn't be sent")
+	}
+}
@@ -0,0 +1,115 @@
+package serial_lcd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"testing"
+)
+
+var (
+	runIntegration = flag.Bool("run-integration", false, "Run benchmarks against a real LCD on -lcd-port.")
+	lcdPort        = flag.String("lcd-port", "/dev/ttyUSB0", "Serial port of the LCD for -run-integration.")
+	lcdBaud        = flag.Int("lcd-baud", 9600, "Baud rate of the LCD for -run-integration.")
+)
+
+// The benchmarks below write to memory, so they measure the overhead of this
+// package rather than the serial link.
+
+// resetEvery keeps a memPort from growing without bound during a benchmark.
+func resetEvery(i int, p *memPort) {
+	if i%1024 == 0 {
+		p.Reset()
+	}
+}
+
+func BenchmarkSetBG(b *testing.B) {
+	orig := MinBGInterval
+	MinBGInterval = 0 // measure the command itself, not the EEPROM guard
+	defer func() { MinBGInterval = orig }()
+	lcd, port := newMemLCD()
+	for i := 0; i < b.N; i++ {
+		resetEvery(i, port)
+		if err := lcd.SetBG(uint8(i), 0, 255); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteText(b *testing.B) {
+	lcd, port := newMemLCD()
+	text := []byte("Hello, world!!!!")
+	b.SetBytes(int64(len(text)))
+	for i := 0; i < b.N; i++ {
+		resetEvery(i, port)
+		if _, err := lcd.Write(text); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFlushDisplayBuffer updates a reading on a Display and flushes it.
+func BenchmarkFlushDisplayBuffer(b *testing.B) {
+	lcd, port := newMemLCD()
+	d := NewDisplay(lcd)
+	d.SetText(0, 0, "Temperature:")
+	for i := 0; i < b.N; i++ {
+		resetEvery(i, port)
+		d.SetText(0, 1, fmt.Sprintf("%6.1fC", float64(i%1000)/10))
+		if err := d.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCommandBatch sends only commands that don't need a pause, since
+// a pause would be measured instead of the batching.
+func BenchmarkCommandBatch(b *testing.B) {
+	lcd, port := newMemLCD()
+	for i := 0; i < b.N; i++ {
+		resetEvery(i, port)
+		err := lcd.Batch(func(l LCD) error {
+			if err := l.MoveTo(1, 1); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(l, "Batched"); err != nil {
+				return err
+			}
+			if err := l.MoveTo(1, 2); err != nil {
+				return err
+			}
+			_, err := fmt.Fprintf(l, "%5d", i%100000)
+			return err
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRealLCDWrite measures how fast text reaches a real display, which
+// is limited by the baud rate.
+func BenchmarkRealLCDWrite(b *testing.B) {
+	if !*runIntegration {
+		b.Skip("needs a display; use -run-integration")
+	}
+	lcd, err := Open(*lcdPort, *lcdBaud)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer lcd.Close()
+	text := []byte("0123456789abcdef")
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := lcd.MoveTo(1, 1); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := lcd.Write(text); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := lcd.Drain(); err != nil {
+		b.Fatal(err)
+	}
+}
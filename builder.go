@@ -0,0 +1,92 @@
+package serial_lcd
+
+// Builder collects display settings so they can be applied together.  Create
+// one with LCD.Begin, chain the settings and finish with Build:
+//
+//	err := lcd.Begin().Cols(16).Rows(2).Brightness(200).BG(0, 0, 255).Build()
+type Builder struct {
+	lcd        LCD
+	cols, rows uint8
+	steps      []func(LCD) error
+}
+
+// Begin starts configuring the display.  Nothing is sent until Build is called.
+func (l LCD) Begin() *Builder { return &Builder{lcd: l} }
+
+// Cols sets the number of columns of the display.  Rows must be set too.
+func (b *Builder) Cols(n uint8) *Builder { b.cols = n; return b }
+
+// Rows sets the number of rows of the display.  Cols must be set too.
+func (b *Builder) Rows(n uint8) *Builder { b.rows = n; return b }
+
+// Brightness sets the backlight brightness, see LCD.SetBrightness.
+func (b *Builder) Brightness(v uint8) *Builder {
+	return b.then(func(l LCD) error { return l.SetBrightness(v) })
+}
+
+// Contrast sets the contrast, see LCD.SetContrast.
+func (b *Builder) Contrast(c uint8) *Builder {
+	return b.then(func(l LCD) error { return l.SetContrast(c) })
+}
+
+// BG sets the backlight color, see LCD.SetBG.
+func (b *Builder) BG(r, g, bl uint8) *Builder {
+	return b.then(func(l LCD) error { return l.SetBG(r, g, bl) })
+}
+
+// Cursor turns the underline and blinking block cursors on or off.
+func (b *Builder) Cursor(underline, block bool) *Builder {
+	return b.then(func(l LCD) error { return l.SetCursorVisible(underline, block) })
+}
+
+// AutoScroll turns autoscrolling on or off, see LCD.SetAutoscroll.
+func (b *Builder) AutoScroll(on bool) *Builder {
+	return b.then(func(l LCD) error { return l.SetAutoscroll(on) })
+}
+
+func (b *Builder) then(step func(LCD) error) *Builder {
+	b.steps = append(b.steps, step)
+	return b
+}
+
+// Build sends all of the settings to the display in a single batch, setting
+// the size first and then everything else in the order it was given.  It
+// returns the first error encountered, or nil on success.  Setting only one of
+// Cols and Rows is an error, and then nothing is sent.
+func (b *Builder) Build() error {
+	if err := checkSize(b.cols, b.rows); err != nil {
+		return err
+	}
+	return b.lcd.Batch(func(l LCD) error {
+		if b.cols != 0 {
+			if err := l.SetSize(b.cols, b.rows); err != nil {
+				return err
+			}
+		}
+		for _, step := range b.steps {
+			if err := step(l); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Configure sets the size, brightness, contrast and background color of the
+// display, which are usually all set together at startup.  They're sent in a
+// single batch, which leaves the backpack time to save each one to EEPROM.  It
+// returns the first error encountered.
+func (l LCD) Configure(cols, rows, brightness, contrast uint8, bg Color) error {
+	return l.Batch(func(l LCD) error {
+		if err := l.SetSize(cols, rows); err != nil {
+			return err
+		}
+		if err := l.SetContrast(contrast); err != nil {
+			return err
+		}
+		if err := l.SetBrightness(brightness); err != nil {
+			return err
+		}
+		return l.SetBG(bg.R, bg.G, bg.B)
+	})
+}
@@ -0,0 +1,78 @@
+package serial_lcd
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Cell is a single character cell of a Display.  Its methods update the
+// display's buffer and then flush it to the LCD.
+type Cell struct {
+	d        *Display
+	col, row uint8
+}
+
+// Cell returns the cell at col,row of the display, counting from 0.
+func (d *Display) Cell(col, row uint8) (*Cell, error) {
+	if col >= d.cols || row >= d.rows {
+		return nil, fmt.Errorf("serial_lcd: cell %d,%d is out of range, the display is %dx%d", col, row, d.cols, d.rows)
+	}
+	return &Cell{d, col, row}, nil
+}
+
+// Set changes the character in the cell.
+func (c *Cell) Set(b byte) error {
+	c.d.mu.Lock()
+	c.d.cells[c.row][c.col] = b
+	c.d.mu.Unlock()
+	return c.d.Flush()
+}
+
+// SetCustomChar shows the custom character in slot (0-7) in the cell.
+func (c *Cell) SetCustomChar(slot uint8) error {
+	if err := checkSlot(slot); err != nil {
+		return err
+	}
+	return c.Set(slot)
+}
+
+// Clear blanks the cell.
+func (c *Cell) Clear() error { return c.Set(' ') }
+
+// Blink makes the cell alternate between its current character and a blank
+// every interval, such as for a status indicator.  The blinking runs in a new
+// goroutine until ctx is cancelled, when the character is restored.  Errors
+// while blinking are reported by the display's next Flush.
+func (c *Cell) Blink(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("serial_lcd: invalid blink interval %v", interval)
+	}
+	c.d.mu.Lock()
+	orig := c.d.cells[c.row][c.col]
+	c.d.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for on := false; ; on = !on {
+			val := byte(' ')
+			select {
+			case <-ctx.Done():
+				val = orig
+			case <-ticker.C:
+				if on {
+					val = orig
+				}
+			}
+			c.d.mu.Lock()
+			c.d.cells[c.row][c.col] = val
+			c.d.backgroundFlushLocked()
+			c.d.mu.Unlock()
+			c.d.notify()
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,168 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Equal reports whether c and other define the same glyph.
+func (c Char) Equal(other Char) bool { return c == other }
+
+// String draws the glyph as 8 lines of 5 characters separated by newlines,
+// with '*' for pixels that are on and '.' for pixels that are off.  It's the
+// inverse of MakeChar.
+func (c Char) String() string {
+	var b strings.Builder
+	for i, row := range c {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		for bit := 4; bit >= 0; bit-- {
+			if row&(1<<uint(bit)) != 0 {
+				b.WriteByte('*')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+	}
+	return b.String()
+}
+
+// MarshalBinary encodes the glyph as its 8 row bytes.
+func (c Char) MarshalBinary() ([]byte, error) { return append([]byte(nil), c[:]...), nil }
+
+// UnmarshalBinary decodes a glyph encoded by MarshalBinary.
+func (c *Char) UnmarshalBinary(data []byte) error {
+	if len(data) != len(c) {
+		return fmt.Errorf("serial_lcd: a Char is %d bytes, got %d", len(c), len(data))
+	}
+	copy(c[:], data)
+	return nil
+}
+
+// MarshalJSON encodes the glyph as an array of its 8 row values.
+func (c Char) MarshalJSON() ([]byte, error) { return json.Marshal([8]byte(c)) }
+
+// UnmarshalJSON decodes a glyph from either an array of 8 row values or a
+// base64 string of the 8 row bytes.
+func (c *Char) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		raw, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		return c.UnmarshalBinary(raw)
+	}
+	var rows []int
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return err
+	}
+	if len(rows) != len(c) {
+		return fmt.Errorf("serial_lcd: a Char has %d rows, got %d", len(c), len(rows))
+	}
+	for i, r := range rows {
+		if r < 0 || r > 0xFF {
+			return fmt.Errorf("serial_lcd: Char row %d is out of range: %d", i, r)
+		}
+		c[i] = byte(r)
+	}
+	return nil
+}
+
+// Shift moves the glyph dx pixels to the right and dy pixels down within its
+// 5x8 cell; negative values move it left and up.  Pixels moved out of the cell
+// are lost and the vacated pixels are off.  Shifting by a pixel at a time is
+// an easy way to animate a glyph.
+func (c Char) Shift(dx, dy int) Char {
+	var out Char
+	for i := range out {
+		src := i - dy
+		if src < 0 || src >= len(c) {
+			continue
+		}
+		row := c[src] & 0x1F
+		if dx > 0 {
+			row >>= uint(dx)
+		} else {
+			row <<= uint(-dx)
+		}
+		out[i] = row & 0x1F
+	}
+	return out
+}
+
+// pixel reports whether the pixel at column x, row y of the glyph is on.
+func (c Char) pixel(x, y int) bool { return c[y]&(0x10>>uint(x)) != 0 }
+
+// Crop extracts the w by h pixel region of the glyph whose top left corner is
+// at column x, row y and scales it to fill the whole 5x8 cell.  The region is
+// clipped to the cell.
+func (c Char) Crop(x, y, w, h int) Char {
+	x0, y0 := max(x, 0), max(y, 0)
+	x1, y1 := min(x+w, 5), min(y+h, len(c))
+	if x1 <= x0 || y1 <= y0 {
+		return Char{}
+	}
+	region := make([][]bool, y1-y0)
+	for j := range region {
+		region[j] = make([]bool, x1-x0)
+		for i := range region[j] {
+			region[j][i] = c.pixel(x0+i, y0+j)
+		}
+	}
+	return ScaleCharDown(region, 5, len(c))
+}
+
+// ScaleCharDown resamples a pixel grid of any size, indexed as rows[y][x], to
+// targetW by targetH pixels and returns it as a glyph with the image in the
+// top left corner.  The target size is limited to the 5x8 cell.  Each target
+// pixel is on if at least half of the source pixels it covers are on, so that
+// high resolution source art keeps its overall shape.
+func ScaleCharDown(rows [][]bool, targetW, targetH int) Char {
+	var c Char
+	srcH, srcW := len(rows), 0
+	for _, row := range rows {
+		srcW = max(srcW, len(row))
+	}
+	targetW, targetH = min(max(targetW, 0), 5), min(max(targetH, 0), len(c))
+	if srcW == 0 || srcH == 0 {
+		return c
+	}
+	for ty := 0; ty < targetH; ty++ {
+		y0, y1 := span(ty, targetH, srcH)
+		for tx := 0; tx < targetW; tx++ {
+			x0, x1 := span(tx, targetW, srcW)
+			on, total := 0, 0
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					if x < len(rows[y]) && rows[y][x] {
+						on++
+					}
+					total++
+				}
+			}
+			if 2*on >= total {
+				c[ty] |= 0x10 >> uint(tx)
+			}
+		}
+	}
+	return c
+}
+
+// span returns the range of source pixels covered by target pixel i when
+// scaling from srcN pixels to n pixels.  It always covers at least one pixel,
+// so scaling up repeats source pixels.
+func span(i, n, srcN int) (from, to int) {
+	from, to = i*srcN/n, (i+1)*srcN/n
+	if to <= from {
+		to = from + 1
+	}
+	return from, to
+}
@@ -0,0 +1,165 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestMakeCharRoundTrip(t *testing.T) {
+	arts := [][8]string{
+		{
+			".....",
+			".*.*.",
+			"*.*.*",
+			"*...*",
+			"*...*",
+			".*.*.",
+			"..*..",
+			".....",
+		},
+		{"*****", "*****", "*****", "*****", "*****", "*****", "*****", "*****"},
+		{".....", ".....", ".....", ".....", ".....", ".....", ".....", "....."},
+		{"*....", ".*...", "..*..", "...*.", "....*", "...*.", "..*..", ".*..."},
+	}
+	for _, art := range arts {
+		want := strings.Join(art[:], "\n")
+		if got := MakeChar(art).String(); got != want {
+			t.Errorf("MakeChar(art).String() =\n%s\nwant\n%s", got, want)
+		}
+	}
+
+	// Spaces are off pixels too, and come back as dots.
+	spaced := [8]string{" * * ", "*****"}
+	if got, want := MakeChar(spaced).String(), ".*.*.\n*****\n"+strings.Repeat(".....\n", 5)+"....."; got != want {
+		t.Errorf("MakeChar(spaced).String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCharStringRoundTrip(t *testing.T) {
+	// Every possible row, in every row position.
+	for row := 0; row < len(Char{}); row++ {
+		for bits := byte(0); bits < 32; bits++ {
+			var c Char
+			c[row] = bits
+			var art [8]string
+			copy(art[:], strings.Split(c.String(), "\n"))
+			if got := MakeChar(art); got != c {
+				t.Fatalf("MakeChar(%v.String()) = %v", c, got)
+			}
+		}
+	}
+}
+
+// pixelsOf returns the pixels that MakeChar should set for a line: one per
+// rune of the first 5, on for anything but '.' and ' '.
+func pixelsOf(line string) byte {
+	var pixels byte
+	runes := []rune(line)
+	for i := 0; i < 5; i++ {
+		pixels <<= 1
+		if i < len(runes) && runes[i] != '.' && runes[i] != ' ' {
+			pixels |= 1
+		}
+	}
+	return pixels
+}
+
+// artLine is a random line of glyph art, mostly made of the characters used
+// to draw glyphs so that both off and on pixels are common, but also empty,
+// long and multibyte lines.
+type artLine string
+
+func (artLine) Generate(r *rand.Rand, size int) reflect.Value {
+	chars := []rune{'.', ' ', '*', '#', 'x', 'é', '█', 0}
+	line := make([]rune, r.Intn(12))
+	for i := range line {
+		line[i] = chars[r.Intn(len(chars))]
+	}
+	return reflect.ValueOf(artLine(line))
+}
+
+func TestMakeCharProperties(t *testing.T) {
+	prop := func(art [8]artLine) bool {
+		var lines [8]string
+		for i := range art {
+			lines[i] = string(art[i])
+		}
+		c := MakeChar(lines) // must not panic
+		for i, line := range lines {
+			if c[i] != pixelsOf(line) {
+				t.Logf("line %d %q gave %05b, want %05b", i, line, c[i], pixelsOf(line))
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+	// Arbitrary strings, too.
+	prop2 := func(lines [8]string) bool { return MakeChar(lines) == MakeCharFromStrings(lines[:]) }
+	if err := quick.Check(prop2, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMakeCharFromStringsArtProperties(t *testing.T) {
+	prop := func(art []artLine) bool {
+		lines := make([]string, len(art))
+		for i := range art {
+			lines[i] = string(art[i])
+		}
+		c := MakeCharFromStrings(lines)
+		for i := range c {
+			var want byte
+			if i < len(lines) {
+				want = pixelsOf(lines[i])
+			}
+			if c[i] != want {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMakeCharFromStringsProperties(t *testing.T) {
+	prop := func(lines []string) bool {
+		c := MakeCharFromStrings(lines) // must not panic
+		for i := range c {
+			var want byte
+			if i < len(lines) {
+				want = pixelsOf(lines[i])
+			}
+			if c[i] != want {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreateCustomCharSendsGlyph(t *testing.T) {
+	prop := func(slot uint8, lines [8]string) bool {
+		slot %= NumCustomChars
+		lcd, port := newMemLCD()
+		c := MakeChar(lines)
+		if err := lcd.CreateCustomChar(slot, c); err != nil {
+			return false
+		}
+		return bytes.Equal(port.Bytes(), cmd(CREATE_CUSTOM_CHARACTER, append([]byte{slot}, c[:]...)...))
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+}
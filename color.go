@@ -0,0 +1,166 @@
+package serial_lcd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Color is a backlight color.
+type Color struct{ R, G, B uint8 }
+
+// String returns the color in CSS rgb() notation, like "rgb(255,128,0)".
+func (c Color) String() string { return fmt.Sprintf("rgb(%d,%d,%d)", c.R, c.G, c.B) }
+
+// Hex returns the color in HTML hex notation, like "#FF8000".
+func (c Color) Hex() string { return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B) }
+
+// ParseColor parses a color in the formats returned by String and Hex, or as
+// six hex digits without the leading '#'.
+func ParseColor(s string) (Color, error) {
+	var c Color
+	if strings.HasPrefix(s, "rgb(") {
+		var r, g, b int
+		var rest string // anything after the closing parenthesis
+		t := strings.ReplaceAll(s, " ", "")
+		n, _ := fmt.Sscanf(t, "rgb(%d,%d,%d)%s", &r, &g, &b, &rest)
+		if n != 3 || !strings.HasSuffix(t, ")") || r < 0 || r > 255 || g < 0 || g > 255 || b < 0 || b > 255 {
+			return c, fmt.Errorf("serial_lcd: invalid color %q", s)
+		}
+		return Color{uint8(r), uint8(g), uint8(b)}, nil
+	}
+	rgb, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil || len(rgb) != 3 {
+		return c, fmt.Errorf("serial_lcd: invalid color %q", s)
+	}
+	return Color{rgb[0], rgb[1], rgb[2]}, nil
+}
+
+// Brightness returns the color with each component multiplied by factor, which
+// is limited to the range 0-2.  Components are capped at 255, so brightening
+// a bright color can change its hue.
+func (c Color) Brightness(factor float64) Color {
+	f := math.Max(0, math.Min(factor, 2))
+	return Color{clampByte(float64(c.R) * f), clampByte(float64(c.G) * f), clampByte(float64(c.B) * f)}
+}
+
+// Saturate returns the color with its saturation multiplied by factor, keeping
+// its hue and brightness.  A factor of 0 gives a shade of gray.
+func (c Color) Saturate(factor float64) Color {
+	h, s, v := c.hsv()
+	return fromHSV(h, math.Max(0, math.Min(s*math.Max(factor, 0), 1)), v)
+}
+
+// Hue returns the color with its hue rotated by shift degrees.
+func (c Color) Hue(shift float64) Color {
+	h, s, v := c.hsv()
+	return fromHSV(math.Mod(math.Mod(h+shift, 360)+360, 360), s, v)
+}
+
+// hsv returns the color's hue in degrees [0,360) and its saturation and value
+// in [0,1].
+func (c Color) hsv() (h, s, v float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	v, d := hi, hi-lo
+	if hi > 0 {
+		s = d / hi
+	}
+	switch {
+	case d == 0:
+		h = 0
+	case hi == r:
+		h = 60 * math.Mod((g-b)/d+6, 6)
+	case hi == g:
+		h = 60 * ((b-r)/d + 2)
+	default:
+		h = 60 * ((r-g)/d + 4)
+	}
+	return h, s, v
+}
+
+func fromHSV(h, s, v float64) Color {
+	f := func(n float64) uint8 {
+		k := math.Mod(n+h/60, 6)
+		return clampByte(255 * (v - v*s*math.Max(0, math.Min(k, math.Min(4-k, 1)))))
+	}
+	return Color{f(5), f(3), f(1)}
+}
+
+// Palette maps names to colors, so that colors can be chosen by name.
+type Palette map[string]Color
+
+// DefaultPalette contains common colors.
+var DefaultPalette = Palette{
+	"red":        {255, 0, 0},
+	"green":      {0, 255, 0},
+	"blue":       {0, 0, 255},
+	"white":      {255, 255, 255},
+	"cyan":       {0, 255, 255},
+	"magenta":    {255, 0, 255},
+	"yellow":     {255, 255, 0},
+	"orange":     {255, 128, 0},
+	"purple":     {128, 0, 255},
+	"pink":       {255, 105, 180},
+	"warm_white": {255, 167, 87},  // 2700K
+	"cool_white": {255, 254, 250}, // 6500K
+	"off":        {0, 0, 0},
+}
+
+// Add adds a named color to the palette, replacing any color with that name.
+func (p Palette) Add(name string, c Color) { p[name] = c }
+
+// Set sets the backlight of lcd to the named color.
+func (p Palette) Set(lcd LCD, name string) error {
+	c, ok := p[name]
+	if !ok {
+		return fmt.Errorf("serial_lcd: no color named %q", name)
+	}
+	return lcd.SetBG(c.R, c.G, c.B)
+}
+
+// SetBackgroundRGB24 sets the background color from a packed 0xRRGGBB value,
+// such as one from a color picker.  The top 8 bits are ignored.
+func (l LCD) SetBackgroundRGB24(rgb uint32) error {
+	return l.SetBG(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb))
+}
+
+// SetBackgroundKelvin sets the backlight to approximate the color of white
+// light at the given color temperature, roughly 1000K (candle) to 12000K (blue
+// sky).  Around 2700K is a warm white and 6500K a cool white.
+func (l LCD) SetBackgroundKelvin(k float64) error { return l.SetBG(kelvinToRGB(k)) }
+
+// ColorTemperature returns the backlight color that approximates white light
+// at the given color temperature, clamped to 1000K-12000K.  Typical values are
+// 2700K for a warm white, 5500K for daylight and 6500K for a cool white.
+func ColorTemperature(kelvin int) Color {
+	r, g, b := kelvinToRGB(float64(min(max(kelvin, 1000), 12000)))
+	return Color{r, g, b}
+}
+
+// kelvinToRGB approximates the color of black-body radiation at temperature k
+// using Tanner Helland's curve fit.
+func kelvinToRGB(k float64) (r, g, b uint8) {
+	t := math.Max(1000, math.Min(k, 40000)) / 100
+	var rf, gf, bf float64
+	if t <= 66 {
+		rf = 255
+		gf = 99.4708025861*math.Log(t) - 161.1195681661
+	} else {
+		rf = 329.698727446 * math.Pow(t-60, -0.1332047592)
+		gf = 288.1221695283 * math.Pow(t-60, -0.0755148492)
+	}
+	switch {
+	case t >= 66:
+		bf = 255
+	case t <= 19:
+		bf = 0
+	default:
+		bf = 138.5177312231*math.Log(t-10) - 305.0447927307
+	}
+	return clampByte(rf), clampByte(gf), clampByte(bf)
+}
+
+// clampByte rounds v to the nearest value in 0-255.
+func clampByte(v float64) uint8 { return uint8(math.Round(math.Max(0, math.Min(v, 255)))) }
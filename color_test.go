@@ -0,0 +1,32 @@
+package serial_lcd
+
+import "testing"
+
+func TestParseColor(t *testing.T) {
+	for s, want := range map[string]Color{
+		"rgb(255,128,0)":     {255, 128, 0},
+		"rgb( 1, 2, 3 )":     {1, 2, 3},
+		"#FF8000":            {255, 128, 0},
+		"ff8000":             {255, 128, 0},
+		Color{9, 8, 7}.Hex(): {9, 8, 7},
+	} {
+		if got, err := ParseColor(s); err != nil || got != want {
+			t.Errorf("ParseColor(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{
+		"rgb(1,2,3)xyz",
+		"rgb(1,2,3))",
+		"rgb(1,2,3",
+		"rgb(1,2)",
+		"rgb(1,2,256)",
+		"rgb(-1,2,3)",
+		"#FF80",
+		"#FF800000",
+		"red",
+	} {
+		if c, err := ParseColor(s); err == nil {
+			t.Errorf("ParseColor(%q) = %v, want an error", s, c)
+		}
+	}
+}
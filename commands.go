@@ -0,0 +1,56 @@
+package serial_lcd
+
+import "time"
+
+// commandInfo describes a backpack command.
+type commandInfo struct {
+	name string
+	args int // number of argument bytes, or -1 for the rest of the write
+
+	// delay is how long the backpack needs after the command before it can
+	// reliably take more input.  Writes that are sent one at a time are
+	// usually slow enough on their own, but a batch sends them back to back.
+	delay time.Duration
+}
+
+// commandLen returns the length of the command that p starts with, including
+// its arguments.  A command that isn't known is assumed to take the rest of p.
+func commandLen(p []byte, q FirmwareQuirks) int {
+	if len(p) < 2 {
+		return len(p)
+	}
+	info, ok := commands[p[1]]
+	if !ok || info.args < 0 {
+		return len(p)
+	}
+	if p[1] == BACKLIGHT_ON && q&QuirkNoBacklightOnArg != 0 {
+		info.args = 0
+	}
+	return min(2+info.args, len(p))
+}
+
+// commands describes the commands that this package sends.
+var commands = map[byte]commandInfo{
+	BACKLIGHT_ON:                            {"BACKLIGHT_ON", 1, 0}, // see QuirkNoBacklightOnArg
+	BACKLIGHT_OFF:                           {"BACKLIGHT_OFF", 0, 0},
+	BRIGHTNESS:                              {"BRIGHTNESS", 1, 10 * time.Millisecond},
+	CONTRAST:                                {"CONTRAST", 1, 10 * time.Millisecond},
+	CLEAR:                                   {"CLEAR", 0, 2 * time.Millisecond},
+	AUTOSCROLL_ON:                           {"AUTOSCROLL_ON", 0, 0},
+	AUTOSCROLL_OFF:                          {"AUTOSCROLL_OFF", 0, 0},
+	SET_STARTUP_SPLASH:                      {"SET_STARTUP_SPLASH", -1, 10 * time.Millisecond},
+	READ_VERSION:                            {"READ_VERSION", 0, 0},
+	SET_CURSOR_POSITION:                     {"SET_CURSOR_POSITION", 2, 0},
+	GO_HOME:                                 {"GO_HOME", 0, 2 * time.Millisecond},
+	CURSOR_BACK:                             {"CURSOR_BACK", 0, 0},
+	CURSOR_FORWARD:                          {"CURSOR_FORWARD", 0, 0},
+	byte(UNDERLINE_CURSOR_ON):               {"UNDERLINE_CURSOR_ON", 0, 0},
+	byte(UNDERLINE_CURSOR_OFF):              {"UNDERLINE_CURSOR_OFF", 0, 0},
+	byte(BLOCK_CURSOR_ON):                   {"BLOCK_CURSOR_ON", 0, 0},
+	byte(BLOCK_CURSOR_OFF):                  {"BLOCK_CURSOR_OFF", 0, 0},
+	SET_RGB_BACKLIGHT_COLOR:                 {"SET_RGB_BACKLIGHT_COLOR", 3, 10 * time.Millisecond},
+	SET_LCD_SIZE:                            {"SET_LCD_SIZE", 2, 10 * time.Millisecond},
+	CREATE_CUSTOM_CHARACTER:                 {"CREATE_CUSTOM_CHARACTER", 9, 5 * time.Millisecond},
+	SAVE_CUSTOM_CHARACTER_TO_EEPROM_BANK:    {"SAVE_CUSTOM_CHARACTER_TO_EEPROM_BANK", 10, 10 * time.Millisecond},
+	LOAD_CUSTOM_CHARACTERS_FROM_EEPROM_BANK: {"LOAD_CUSTOM_CHARACTERS_FROM_EEPROM_BANK", 1, 10 * time.Millisecond},
+}
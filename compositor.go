@@ -0,0 +1,186 @@
+package serial_lcd
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Widget is a reusable piece of a screen, such as a clock or a progress bar,
+// that a Compositor lays out on a Display.
+type Widget interface {
+	// Render draws the widget into buf, which starts out blank.
+	Render(buf *Buffer)
+}
+
+// Buffer is the area of the screen that a Widget draws in.  Its rows and
+// columns count from 0 at the area's top left corner, and anything drawn
+// outside of it is dropped.
+type Buffer struct {
+	cells [][]byte
+}
+
+func newBuffer(cols, rows uint8) *Buffer { return &Buffer{blankGrid(cols, rows)} }
+
+// Size returns the number of columns and rows of the buffer.
+func (b *Buffer) Size() (cols, rows uint8) {
+	if len(b.cells) == 0 {
+		return 0, 0
+	}
+	return uint8(len(b.cells[0])), uint8(len(b.cells))
+}
+
+// SetText draws text starting at col,row.  Text past the end of the row is
+// dropped.
+func (b *Buffer) SetText(col, row uint8, text string) {
+	if int(row) < len(b.cells) && int(col) < len(b.cells[row]) {
+		copy(b.cells[row][col:], text)
+	}
+}
+
+// SetByte sets the character at col,row, such as a custom character slot.
+func (b *Buffer) SetByte(col, row uint8, c byte) {
+	if int(row) < len(b.cells) && int(col) < len(b.cells[row]) {
+		b.cells[row][col] = c
+	}
+}
+
+// Compositor draws widgets in areas of a Display and flushes the result, so
+// that only what changed since the last render is sent to the LCD.
+//
+// For example, a download screen with a title, a clock and a progress bar:
+//
+//	c := serial_lcd.NewCompositor(serial_lcd.NewDisplay(lcd))
+//	bar := &serial_lcd.ProgressBar{}
+//	c.Add(serial_lcd.NewTextField("Download"), 0, 0, 10, 1)
+//	c.Add(serial_lcd.Clock{Format: "15:04"}, 11, 0, 5, 1)
+//	c.Add(bar, 0, 1, 16, 1)
+//	go c.Run(ctx, time.Second)
+//	for progress := range updates {
+//		bar.SetValue(progress)
+//	}
+type Compositor struct {
+	d *Display
+
+	mu      sync.Mutex
+	widgets []placedWidget
+}
+
+type placedWidget struct {
+	w                    Widget
+	col, row, cols, rows uint8
+}
+
+// NewCompositor returns a Compositor that draws on d.
+func NewCompositor(d *Display) *Compositor { return &Compositor{d: d} }
+
+// Add places w in the area cols wide and rows high whose top left corner is at
+// col,row of the display, counting from 0.  Widgets added later are drawn over
+// earlier ones where they overlap.
+func (c *Compositor) Add(w Widget, col, row, cols, rows uint8) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.widgets = append(c.widgets, placedWidget{w, col, row, cols, rows})
+}
+
+// Render draws every widget and flushes the display.
+func (c *Compositor) Render() error {
+	c.mu.Lock()
+	widgets := append([]placedWidget(nil), c.widgets...)
+	c.mu.Unlock()
+	for _, p := range widgets {
+		buf := newBuffer(p.cols, p.rows)
+		p.w.Render(buf)
+		for r, line := range buf.cells {
+			c.d.SetText(p.col, p.row+uint8(r), string(line))
+		}
+	}
+	return c.d.Flush()
+}
+
+// Run renders the widgets every interval until ctx is cancelled, for widgets
+// that change on their own, such as a Clock, and then returns ctx.Err().
+func (c *Compositor) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Render(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// TextField is a Widget showing text, with a line of text per row.  It's safe
+// to change the text while the widget is being rendered.
+type TextField struct {
+	mu   sync.Mutex
+	text string
+}
+
+// NewTextField returns a TextField showing text.
+func NewTextField(text string) *TextField { return &TextField{text: text} }
+
+// SetText changes the text.  It's shown the next time the widget is rendered.
+func (t *TextField) SetText(text string) {
+	t.mu.Lock()
+	t.text = text
+	t.mu.Unlock()
+}
+
+// Render draws the text, splitting it into rows at newlines.
+func (t *TextField) Render(buf *Buffer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for r, line := range strings.Split(t.text, "\n") {
+		buf.SetText(0, uint8(min(r, 255)), line)
+	}
+}
+
+// Clock is a Widget showing the current time, formatted with time.Format.  An
+// empty Format shows hours, minutes and seconds.
+type Clock struct {
+	Format string
+}
+
+// Render draws the current time.
+func (c Clock) Render(buf *Buffer) {
+	format := c.Format
+	if format == "" {
+		format = "15:04:05"
+	}
+	buf.SetText(0, 0, time.Now().Format(format))
+}
+
+// ProgressBar is a Widget showing a horizontal bar across its first row that
+// is filled in proportion to its value.  It's safe to change the value while
+// the widget is being rendered.
+type ProgressBar struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// SetValue sets how full the bar is, from 0 to 1.
+func (p *ProgressBar) SetValue(v float64) {
+	p.mu.Lock()
+	p.value = math.Max(0, math.Min(v, 1))
+	p.mu.Unlock()
+}
+
+// Render draws the bar.
+func (p *ProgressBar) Render(buf *Buffer) {
+	p.mu.Lock()
+	v := p.value
+	p.mu.Unlock()
+	cols, _ := buf.Size()
+	filled := int(math.Round(v * float64(cols)))
+	for i := 0; i < filled; i++ {
+		buf.SetByte(uint8(i), 0, romFullBlock)
+	}
+}
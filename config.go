@@ -0,0 +1,84 @@
+package serial_lcd
+
+import "fmt"
+
+// Config holds the display settings that an app typically saves and restores,
+// such as in its own config file.  Snapshot gets the current settings, and
+// Apply or OpenConfig restores them.
+type Config struct {
+	Cols       uint8 `json:"cols"` // 0 for both leaves the size unchanged
+	Rows       uint8 `json:"rows"`
+	Brightness uint8 `json:"brightness"`
+	Contrast   uint8 `json:"contrast"`
+	BG         Color `json:"bg"`
+	Off        bool  `json:"off"` // whether the backlight is off
+	Autoscroll bool  `json:"autoscroll"`
+}
+
+// Snapshot returns the LCD's current settings, as tracked from the commands
+// sent to it.
+func (l LCD) Snapshot() Config {
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	return Config{
+		Cols:       l.state().cols,
+		Rows:       l.state().rows,
+		Brightness: l.state().brightness,
+		Contrast:   l.state().contrast,
+		BG:         l.state().bg,
+		Off:        l.state().off,
+		Autoscroll: l.state().autoscroll,
+	}
+}
+
+// checkSize returns an error unless cols and rows are both set or both 0.
+func checkSize(cols, rows uint8) error {
+	if (cols == 0) != (rows == 0) {
+		return fmt.Errorf("serial_lcd: invalid size %dx%d, set both cols and rows or neither", cols, rows)
+	}
+	return nil
+}
+
+// Apply sends the settings in c to the LCD in a single batch.  The size is set
+// first, since how the backpack handles the rest of the text depends on it.  It
+// returns the first error encountered.  A size with only one of Cols and Rows
+// set is an error, and then nothing is sent.
+func (l LCD) Apply(c Config) error {
+	if err := checkSize(c.Cols, c.Rows); err != nil {
+		return err
+	}
+	return l.Batch(func(l LCD) error {
+		if c.Cols != 0 {
+			if err := l.SetSize(c.Cols, c.Rows); err != nil {
+				return err
+			}
+		}
+		if err := l.SetAutoscroll(c.Autoscroll); err != nil {
+			return err
+		}
+		if err := l.SetContrast(c.Contrast); err != nil {
+			return err
+		}
+		if err := l.SetBrightness(c.Brightness); err != nil {
+			return err
+		}
+		if err := l.SetBG(c.BG.R, c.BG.G, c.BG.B); err != nil {
+			return err
+		}
+		return l.SetOn(!c.Off)
+	})
+}
+
+// OpenConfig opens the LCD on the given serial port, like Open, and applies c
+// to it.  If applying the settings fails the port is closed again.
+func OpenConfig(port string, baud int, c Config, opts ...Option) (LCD, error) {
+	l, err := Open(port, baud, opts...)
+	if err != nil {
+		return LCD{}, err
+	}
+	if err := l.Apply(c); err != nil {
+		l.Close()
+		return LCD{}, err
+	}
+	return l, nil
+}
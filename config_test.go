@@ -0,0 +1,30 @@
+package serial_lcd
+
+import "testing"
+
+func TestApply_NeedsBothDimensions(t *testing.T) {
+	lcd, port := newMemLCD()
+	for _, c := range []Config{{Cols: 20}, {Rows: 4}} {
+		if err := lcd.Apply(c); err == nil {
+			t.Errorf("Apply(%+v) succeeded with only one dimension", c)
+		}
+	}
+	for _, b := range []*Builder{lcd.Begin().Cols(20), lcd.Begin().Rows(4).Brightness(9)} {
+		if err := b.Build(); err == nil {
+			t.Error("Build succeeded with only one dimension")
+		}
+	}
+	if sent := port.Bytes(); len(sent) != 0 {
+		t.Errorf("sent %q for an invalid size", sent)
+	}
+
+	if err := lcd.Begin().Cols(20).Rows(4).Build(); err != nil {
+		t.Fatal(err)
+	}
+	if err := lcd.Begin().Brightness(9).Build(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := port.Bytes(), cat(cmd(SET_LCD_SIZE, 20, 4), cmd(BRIGHTNESS, 9)); string(got) != string(want) {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,235 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Display is a buffered view of an LCD.  Text is drawn into an in-memory copy
+// of the screen and Flush sends only the characters that changed to the LCD.
+// Unlike the LCD's cursor positions, the rows and columns of a Display count
+// from 0.
+//
+// A Display is safe for concurrent use.
+//
+// For example, to update a reading without redrawing its label:
+//
+//	d := serial_lcd.NewDisplay(lcd)
+//	d.SetText(0, 0, "Temp:")
+//	for t := range temperatures {
+//		d.SetText(6, 0, fmt.Sprintf("%5.1fC", t))
+//		if err := d.Flush(); err != nil { // only the digits that changed are sent
+//			return err
+//		}
+//	}
+type Display struct {
+	lcd LCD
+
+	mu    sync.Mutex
+	cols  uint8
+	rows  uint8
+	cells [][]byte // the display's content, indexed [row][col]
+	shown *Frame   // what the LCD is currently showing
+	err   error    // error from a background flush, reported by Flush
+
+	help     [][]string    // pages of help text
+	helpTime time.Duration // how long all of the help pages are shown for
+	helpPage int           // page being shown, or -1 when help isn't shown
+	helpGen  int           // incremented to cancel pending page changes
+
+	router *Router // sends rows to other displays instead of lcd, if set
+
+	lastSetting time.Time // when the HTTP API last changed brightness or contrast
+
+	listeners    []listener // see OnChange, in the order they were registered
+	nextListener int
+	changed      bool     // whether the text changed since listeners were notified
+	notified     LCDState // the state listeners were last notified of
+}
+
+// NewDisplay returns a Display for lcd, sized to match it.  The display starts
+// out blank; call Flush to clear the LCD to match.
+func NewDisplay(lcd LCD) *Display {
+	cols, rows := lcd.size()
+	return newDisplay(lcd, cols, rows)
+}
+
+func newDisplay(lcd LCD, cols, rows uint8) *Display {
+	d := &Display{lcd: lcd, cols: cols, rows: rows, helpPage: -1}
+	d.cells = blankGrid(cols, rows)
+	d.shown = NewFrame(cols, rows)
+	d.shown.Invalidate() // nothing is known to be on the LCD yet
+	return d
+}
+
+func blankGrid(cols, rows uint8) [][]byte {
+	g := make([][]byte, rows)
+	for r := range g {
+		g[r] = bytes.Repeat([]byte{' '}, int(cols))
+	}
+	return g
+}
+
+// LCD returns the LCD that the display draws on.
+func (d *Display) LCD() LCD { return d.lcd }
+
+// Size returns the number of columns and rows of the display.
+func (d *Display) Size() (cols, rows uint8) { return d.cols, d.rows }
+
+// SetText draws text starting at col,row.  Text past the end of the row is
+// dropped.  The LCD isn't updated until Flush is called.
+func (d *Display) SetText(col, row uint8, text string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if row < d.rows && col < d.cols {
+		copy(d.cells[row][col:], text)
+	}
+}
+
+// Clear blanks the display.  The LCD isn't updated until Flush is called.
+func (d *Display) Clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cells = blankGrid(d.cols, d.rows)
+}
+
+// Flush updates the LCD to match the display, sending only what changed since
+// the last flush.  It also reports any error from updating the LCD in the
+// background, such as when advancing help pages.
+func (d *Display) Flush() error {
+	defer d.notify()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := d.flushLocked()
+	if d.err != nil {
+		err, d.err = d.err, nil
+	}
+	return err
+}
+
+func (d *Display) flushLocked() error {
+	want := &Frame{Cols: d.cols, Rows: d.rows, Cells: d.cells}
+	if d.helpPage >= 0 {
+		want = NewFrame(d.cols, d.rows)
+		for r, line := range d.help[d.helpPage] {
+			want.SetText(0, uint8(r), line)
+		}
+	}
+	if d.router != nil {
+		return d.router.flush(d, want.Cells)
+	}
+	// Send each run of changed cells in a row with a single move and write, so
+	// the cursor only moves where unchanged cells are skipped.
+	changes := d.shown.Diff(want)
+	return d.lcd.exclusive(func(lcd LCD) error {
+		for i := 0; i < len(changes); {
+			start, end := changes[i], i+1
+			for end < len(changes) && changes[end].Row == start.Row && changes[end].Col == changes[end-1].Col+1 {
+				end++
+			}
+			run := make([]byte, 0, end-i)
+			for _, ch := range changes[i:end] {
+				run = append(run, ch.Value)
+			}
+			if err := lcd.MoveTo(start.Col+1, start.Row+1); err != nil {
+				return err
+			}
+			if err := dropN(lcd.Write(run)); err != nil {
+				return err
+			}
+			d.shown.SetText(start.Col, start.Row, string(run))
+			d.changed = true
+			i = end
+		}
+		return nil
+	})
+}
+
+// backgroundFlushLocked flushes the display for a background update, saving
+// any error to be reported by the next call to Flush.
+func (d *Display) backgroundFlushLocked() {
+	if err := d.flushLocked(); err != nil && d.err == nil {
+		d.err = err
+	}
+}
+
+// SetHelpMode shows a help or info message for the given duration before the
+// display's own content, such as instructions when a kiosk starts up.  Text
+// that doesn't fit on the screen is split into pages that are shown in turn,
+// each for an equal share of duration.  Use ForceHelp to show it again later.
+func (d *Display) SetHelpMode(text string, duration time.Duration) {
+	d.mu.Lock()
+	d.help = paginate(text, int(d.cols), int(d.rows))
+	d.helpTime = duration
+	d.mu.Unlock()
+	d.ForceHelp()
+}
+
+// ForceHelp shows the help message set by SetHelpMode again from its first
+// page, e.g. when a help button is pressed.
+func (d *Display) ForceHelp() {
+	defer d.notify()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.help) == 0 {
+		return
+	}
+	d.helpGen++
+	d.showHelpPageLocked(0, d.helpGen)
+}
+
+func (d *Display) showHelpPageLocked(page, gen int) {
+	if gen != d.helpGen {
+		return // help was restarted
+	}
+	if page >= len(d.help) {
+		page = -1
+	}
+	d.helpPage = page
+	d.backgroundFlushLocked()
+	if page >= 0 {
+		time.AfterFunc(d.helpTime/time.Duration(len(d.help)), func() {
+			defer d.notify()
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.showHelpPageLocked(page+1, gen)
+		})
+	}
+}
+
+// paginate word-wraps text to the given width, splitting it into pages of the
+// given number of lines.  Newlines in the text start new lines.
+func paginate(text string, width, lines int) [][]string {
+	if width < 1 || lines < 1 {
+		return nil
+	}
+	var wrapped []string
+	for _, para := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			for len(word) > width {
+				if line != "" {
+					wrapped, line = append(wrapped, line), ""
+				}
+				wrapped, word = append(wrapped, word[:width]), word[width:]
+			}
+			switch {
+			case line == "":
+				line = word
+			case len(line)+1+len(word) <= width:
+				line += " " + word
+			default:
+				wrapped, line = append(wrapped, line), word
+			}
+		}
+		wrapped = append(wrapped, line)
+	}
+	var pages [][]string
+	for len(wrapped) > 0 {
+		n := min(lines, len(wrapped))
+		pages, wrapped = append(pages, wrapped[:n]), wrapped[n:]
+	}
+	return pages
+}
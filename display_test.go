@@ -0,0 +1,65 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDisplay_FirstFlushSendsEveryCell(t *testing.T) {
+	lcd, port := newMemLCD()
+	d := NewDisplay(lcd)
+	d.SetText(0, 0, "\x00 slot 0")
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := cat(
+		cmd(SET_CURSOR_POSITION, 1, 1), []byte("\x00 slot 0        "),
+		cmd(SET_CURSOR_POSITION, 1, 2), bytes.Repeat([]byte{' '}, 16),
+	)
+	if got := port.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("first Flush sent %q, want %q", got, want)
+	}
+
+	port.Reset()
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := port.Bytes(); len(got) != 0 {
+		t.Errorf("Flush without changes sent %q", got)
+	}
+}
+
+func TestFrame_Invalidate(t *testing.T) {
+	f, other := NewFrame(4, 2), NewFrame(4, 2)
+	other.SetCustomChar(1, 0, 0)
+	f.Invalidate()
+	if n := len(f.Diff(other)); n != 8 {
+		t.Errorf("Diff from an invalidated frame has %d changes, want all 8", n)
+	}
+	// Cells that are set are compared again, even to slot 0.
+	f.SetText(0, 0, "a\x00")
+	f.SetCustomChar(3, 1, 0)
+	want := []CellChange{{0, 0, ' '}, {2, 0, ' '}, {3, 0, ' '}, {0, 1, ' '}, {1, 1, ' '}, {2, 1, ' '}, {3, 1, ' '}}
+	got := f.Diff(other)
+	if len(got) != len(want) {
+		t.Fatalf("Diff = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("Diff = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCompositor_RunReturnsCtxErr(t *testing.T) {
+	lcd, _ := newMemLCD()
+	c := NewCompositor(NewDisplay(lcd))
+	c.Add(NewTextField("hello"), 0, 0, 16, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Run(ctx, time.Millisecond); err != context.Canceled {
+		t.Errorf("Run returned %v after cancel, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,108 @@
+package serial_lcd
+
+// LCDState is a snapshot of what a Display's LCD is showing.
+type LCDState struct {
+	Cols       uint8    `json:"cols"`
+	Rows       uint8    `json:"rows"`
+	Lines      []string `json:"lines"` // the text of each row, as sent to the LCD
+	BG         Color    `json:"bg"`    // background color
+	On         bool     `json:"on"`    // whether the backlight is on
+	Brightness uint8    `json:"brightness"`
+	Contrast   uint8    `json:"contrast"`
+	CursorCol  uint8    `json:"cursor_col"` // counting from 0, like Display
+	CursorRow  uint8    `json:"cursor_row"`
+}
+
+// sameSettings reports whether a and b differ only in their text.
+func sameSettings(a, b LCDState) bool {
+	return a.Cols == b.Cols && a.Rows == b.Rows && a.BG == b.BG && a.On == b.On &&
+		a.Brightness == b.Brightness && a.Contrast == b.Contrast &&
+		a.CursorCol == b.CursorCol && a.CursorRow == b.CursorRow
+}
+
+type listener struct {
+	id    int // for unregistering
+	fn    func(LCDState)
+	async bool
+}
+
+// OnChange registers fn to be called with the display's new state after each
+// flush that changes what's on the LCD, including its settings such as the
+// background color, e.g. to update a web page showing the
+// display.  fn runs in the goroutine that flushed, after the display has been
+// unlocked, so it may use the display.  Listeners are called in the order they
+// were registered.  The returned function unregisters fn.
+func (d *Display) OnChange(fn func(snapshot LCDState)) (cancel func()) {
+	return d.addListener(listener{fn: fn})
+}
+
+// OnChangeAsync is like OnChange, but calls fn in a new goroutine so that a
+// slow listener doesn't hold up the code updating the display.  Calls for
+// successive changes may run concurrently.
+func (d *Display) OnChangeAsync(fn func(snapshot LCDState)) (cancel func()) {
+	return d.addListener(listener{fn: fn, async: true})
+}
+
+func (d *Display) addListener(l listener) func() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	l.id = d.nextListener
+	d.nextListener++
+	d.listeners = append(d.listeners, l)
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for i := range d.listeners {
+			if d.listeners[i].id == l.id {
+				// Copy rather than shift in place, since notify may be
+				// calling the listeners from the old slice.
+				d.listeners = append(d.listeners[:i:i], d.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notify calls the listeners if the LCD has changed since the last call.  The
+// display must not be locked.
+func (d *Display) notify() {
+	d.mu.Lock()
+	snap := d.stateLocked()
+	changed := d.changed || !sameSettings(snap, d.notified)
+	d.changed, d.notified = false, snap
+	if !changed || len(d.listeners) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	listeners := d.listeners // never changed in place, see addListener
+	d.mu.Unlock()
+
+	for _, l := range listeners {
+		if l.async {
+			go l.fn(snap)
+		} else {
+			l.fn(snap)
+		}
+	}
+}
+
+// State returns a snapshot of what the LCD is showing.
+func (d *Display) State() LCDState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stateLocked()
+}
+
+func (d *Display) stateLocked() LCDState {
+	snap := LCDState{Cols: d.cols, Rows: d.rows}
+	for _, row := range d.shown.Cells {
+		snap.Lines = append(snap.Lines, string(row))
+	}
+	s := d.lcd.state()
+	s.mu.Lock()
+	snap.BG, snap.On, snap.Brightness, snap.Contrast = s.bg, !s.off, s.brightness, s.contrast
+	s.mu.Unlock()
+	col, row := d.lcd.CursorPos()
+	snap.CursorCol, snap.CursorRow = col-1, row-1
+	return snap
+}
@@ -0,0 +1,27 @@
+package serial_lcd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOnChange_CallsListenersInOrder(t *testing.T) {
+	lcd, _ := newMemLCD()
+	d := NewDisplay(lcd)
+	var calls []int
+	for i := 0; i < 10; i++ {
+		i := i
+		d.OnChange(func(LCDState) { calls = append(calls, i) })
+	}
+	cancel := d.OnChange(func(LCDState) { t.Error("cancelled listener was called") })
+	d.OnChange(func(LCDState) { calls = append(calls, 10) })
+	cancel()
+
+	d.SetText(0, 0, "hello")
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("listeners were called in the order %v, want %v", calls, want)
+	}
+}
@@ -0,0 +1,141 @@
+package serial_lcd_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/augustoroman/serial_lcd"
+)
+
+// port is an in-memory stand-in for a serial port, so that the examples can
+// show exactly what's sent to the display.
+type port struct{ bytes.Buffer }
+
+func (*port) Close() error { return nil }
+
+func ExampleOpen() {
+	lcd, err := serial_lcd.Open("/dev/ttyUSB0", 9600, serial_lcd.WithRetry(5, time.Second))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer lcd.Close()
+	if err := lcd.Configure(16, 2, 255, 200, serial_lcd.DefaultPalette["white"]); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprint(lcd, "Hello, world!")
+}
+
+func ExampleLCD_SetBG() {
+	// Show the alert level in the backlight color.
+	lcd := serial_lcd.NullLCD()
+	colors := map[string]serial_lcd.Color{
+		"ok":      {R: 0, G: 255, B: 0},
+		"warning": {R: 255, G: 160, B: 0},
+		"alarm":   {R: 255, G: 0, B: 0},
+	}
+	level := "warning"
+	c := colors[level]
+	if err := lcd.SetBG(c.R, c.G, c.B); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(lcd.Snapshot().BG)
+	// Output: rgb(255,160,0)
+}
+
+func ExampleMakeChar() {
+	heart := serial_lcd.MakeChar([8]string{
+		".....",
+		".*.*.",
+		"*.*.*",
+		"*...*",
+		"*...*",
+		".*.*.",
+		"..*..",
+		".....",
+	})
+	fmt.Printf("% x\n", heart[:])
+	fmt.Println(heart)
+	// Output:
+	// 00 0a 15 11 11 0a 04 00
+	// .....
+	// .*.*.
+	// *.*.*
+	// *...*
+	// *...*
+	// .*.*.
+	// ..*..
+	// .....
+}
+
+func ExampleLCD_CreateCustomChar() {
+	p := &port{}
+	lcd := serial_lcd.New(p)
+	heart := serial_lcd.MakeChar([8]string{
+		".....",
+		".*.*.",
+		"*.*.*",
+		"*...*",
+		"*...*",
+		".*.*.",
+		"..*..",
+		".....",
+	})
+	lcd.CreateCustomChar(1, heart)
+	fmt.Fprint(lcd, "I")
+	lcd.PrintChar(1)
+	fmt.Fprint(lcd, "Go")
+	fmt.Printf("% x\n", p.Bytes())
+	// Output: fe 4e 01 00 0a 15 11 11 0a 04 00 49 01 47 6f
+}
+
+func ExampleDisplay() {
+	d := serial_lcd.NewDisplay(serial_lcd.NullLCD())
+	d.SetText(0, 0, "Temp:")
+	for _, t := range []float64{21.5, 21.7, 22.0} {
+		d.SetText(6, 0, fmt.Sprintf("%5.1fC", t))
+		if err := d.Flush(); err != nil { // sends only the digits that changed
+			log.Fatal(err)
+		}
+	}
+	for _, line := range d.State().Lines {
+		fmt.Printf("%q\n", line)
+	}
+	// Output:
+	// "Temp:  22.0C    "
+	// "                "
+}
+
+func ExampleLCD_Marquee() {
+	lcd := serial_lcd.NullLCD()
+	lcd.SetLine(1, "Departures")
+	// Scroll the next trains across the second row for a minute.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err := lcd.Marquee(ctx, 2, "08:15 Central  08:22 Airport  08:30 Harbour", 300*time.Millisecond)
+	if err != context.DeadlineExceeded {
+		log.Fatal(err)
+	}
+}
+
+func ExampleProgressBar() {
+	d := serial_lcd.NewDisplay(serial_lcd.NullLCD())
+	c := serial_lcd.NewCompositor(d)
+	bar := &serial_lcd.ProgressBar{}
+	c.Add(serial_lcd.NewTextField("Copying files"), 0, 0, 16, 1)
+	c.Add(bar, 0, 1, 16, 1)
+	for _, done := range []int{2, 5, 8} {
+		bar.SetValue(float64(done) / 10)
+		if err := c.Render(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, line := range d.State().Lines {
+		fmt.Printf("%q\n", line)
+	}
+	// Output:
+	// "Copying files   "
+	// "\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff   "
+}
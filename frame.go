@@ -0,0 +1,97 @@
+package serial_lcd
+
+// Frame is a picture of the screen's content, such as what's to be shown next,
+// kept in memory.  Its rows and columns count from 0, like Display's, and Cells
+// is indexed [row][col].  Comparing two frames with Diff gives the cells that
+// need to be sent to change the display from one to the other.
+type Frame struct {
+	Cols, Rows uint8
+	Cells      [][]byte
+
+	unknown [][]bool // cells whose content isn't known, see Invalidate
+}
+
+// NewFrame returns a blank frame of the given size.
+func NewFrame(cols, rows uint8) *Frame {
+	return &Frame{Cols: cols, Rows: rows, Cells: blankGrid(cols, rows)}
+}
+
+// SetText draws text starting at col,row.  Text past the end of the row is
+// dropped.
+func (f *Frame) SetText(col, row uint8, text string) {
+	if row < f.Rows && col < f.Cols {
+		n := copy(f.Cells[row][col:], text)
+		f.know(col, row, n)
+	}
+}
+
+// SetCustomChar places the custom character in slot (0-7) at col,row.
+// Invalid slots are ignored.
+func (f *Frame) SetCustomChar(col, row uint8, slot uint8) {
+	if row < f.Rows && col < f.Cols && slot < NumCustomChars {
+		f.Cells[row][col] = slot
+		f.know(col, row, 1)
+	}
+}
+
+// Fill sets every cell to b.
+func (f *Frame) Fill(b byte) {
+	for _, row := range f.Cells {
+		for c := range row {
+			row[c] = b
+		}
+	}
+	f.unknown = nil
+}
+
+// Invalidate marks every cell as unknown, such as when the screen's content
+// was lost, until it's set again.  Diff reports unknown cells of f as changed
+// whatever they are in the other frame, and they read as spaces.  Any byte is
+// a valid cell, custom character 0 included, so no value can mark them
+// instead.
+func (f *Frame) Invalidate() {
+	f.Cells = blankGrid(f.Cols, f.Rows)
+	f.unknown = make([][]bool, f.Rows)
+	for r := range f.unknown {
+		f.unknown[r] = make([]bool, f.Cols)
+		for c := range f.unknown[r] {
+			f.unknown[r][c] = true
+		}
+	}
+}
+
+// know marks n cells starting at col,row as known.
+func (f *Frame) know(col, row uint8, n int) {
+	if f.unknown != nil && int(row) < len(f.unknown) {
+		u := f.unknown[row][min(int(col), len(f.unknown[row])):]
+		for i := range u[:min(n, len(u))] {
+			u[i] = false
+		}
+	}
+}
+
+// isUnknown reports whether the cell at col,row is unknown.
+func (f *Frame) isUnknown(col, row int) bool {
+	return f.unknown != nil && row < len(f.unknown) && col < len(f.unknown[row]) && f.unknown[row][col]
+}
+
+// CellChange is a cell whose content differs between two frames.
+type CellChange struct {
+	Col, Row uint8
+	Value    byte // the cell's content in the new frame
+}
+
+// Diff returns the cells that differ in other, in order from the top left, for
+// changing a display that shows f to show other.  Only the cells that are in
+// both frames are compared, and cells that are unknown in f always differ.
+func (f *Frame) Diff(other *Frame) []CellChange {
+	var changes []CellChange
+	for r := 0; r < int(min(f.Rows, other.Rows)); r++ {
+		for c := 0; c < int(min(f.Cols, other.Cols)); c++ {
+			if v := other.Cells[r][c]; v != f.Cells[r][c] || f.isUnknown(c, r) {
+				changes = append(changes, CellChange{uint8(c), uint8(r), v})
+			}
+		}
+	}
+	return changes
+}
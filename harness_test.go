@@ -0,0 +1,142 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newPipePair returns the two ends of an in-memory serial link, standing in
+// for a loopback pair of serial ports: bytes written to lcd can be read from
+// driver, and the other way around.  Both ends are closed when the test ends.
+func newPipePair(t testing.TB) (driver, lcd net.Conn) {
+	driver, lcd = net.Pipe()
+	t.Cleanup(func() {
+		driver.Close()
+		lcd.Close()
+	})
+	return driver, lcd
+}
+
+// fakeOpen makes Open connect to rwc instead of a serial port until the test
+// ends.
+func fakeOpen(t testing.TB, rwc io.ReadWriteCloser) {
+	orig := openPort
+	openPort = func(string, int) (io.ReadWriteCloser, error) { return rwc, nil }
+	t.Cleanup(func() { openPort = orig })
+}
+
+// wire collects everything the LCD sends over a pipe so that tests can check
+// it as it arrives.
+type wire struct {
+	t    testing.TB
+	data chan byte
+}
+
+// listen starts reading driver in the background.
+func listen(t testing.TB, driver net.Conn) *wire {
+	w := &wire{t, make(chan byte, 4096)}
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := driver.Read(buf)
+			for _, b := range buf[:n] {
+				w.data <- b
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// expect fails the test unless the next bytes sent are want.
+func (w *wire) expect(want ...byte) {
+	w.t.Helper()
+	got := make([]byte, 0, len(want))
+	timeout := time.After(time.Second)
+	for len(got) < len(want) {
+		select {
+		case b := <-w.data:
+			got = append(got, b)
+		case <-timeout:
+			w.t.Fatalf("sent %q, want %q", got, want)
+		}
+	}
+	if !bytes.Equal(got, want) {
+		w.t.Fatalf("sent %q, want %q", got, want)
+	}
+}
+
+// expectNothing fails the test if anything more has been sent.
+func (w *wire) expectNothing() {
+	w.t.Helper()
+	select {
+	case b := <-w.data:
+		w.t.Fatalf("unexpectedly sent %q", b)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// memPort is a serial port that keeps everything written to it in memory.
+// Reads return io.EOF, as if the backpack never replies.
+type memPort struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+	slow   bool // sleep briefly after each write, like a real port
+}
+
+func (p *memPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	n, err := p.buf.Write(b)
+	slow := p.slow
+	p.mu.Unlock()
+	if slow {
+		time.Sleep(50 * time.Microsecond)
+	}
+	return n, err
+}
+
+func (p *memPort) Read([]byte) (int, error) { return 0, io.EOF }
+
+func (p *memPort) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	return nil
+}
+
+// Bytes returns everything written so far.
+func (p *memPort) Bytes() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]byte(nil), p.buf.Bytes()...)
+}
+
+// Reset forgets everything written so far.
+func (p *memPort) Reset() {
+	p.mu.Lock()
+	p.buf.Reset()
+	p.mu.Unlock()
+}
+
+// newMemLCD returns an LCD that writes to a memPort.
+func newMemLCD() (LCD, *memPort) {
+	p := &memPort{}
+	return New(p), p
+}
+
+// cmd returns the bytes of a backpack command.
+func cmd(c byte, args ...byte) []byte { return append([]byte{COMMAND, c}, args...) }
+
+// cat joins byte slices.
+func cat(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
@@ -0,0 +1,111 @@
+package serial_lcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ServeHTTP serves a JSON API for controlling the display, so that it can be
+// used from any HTTP client:
+//
+//	GET    /api/state       the display's LCDState
+//	POST   /api/text        {"col": 0, "row": 0, "text": "hello"}
+//	POST   /api/brightness  {"value": 200}
+//	POST   /api/contrast    {"value": 200}
+//	POST   /api/color       {"r": 255, "g": 0, "b": 0}
+//	POST   /api/backlight   {"on": true}
+//	DELETE /api/display     clears the display
+//
+// Like the rest of Display, rows and columns count from 0, and text outside
+// the display is refused with 400 Bad Request.  Text is drawn and flushed
+// right away.  Successful updates reply with 204 No Content.
+//
+// The backpack saves the brightness, contrast and color to its EEPROM, which
+// wears out after around 100,000 writes.  Like SetBG, the API waits as needed
+// so that the brightness and contrast together aren't changed more often than
+// MinBGInterval, so a client dragging a slider slows down rather than wearing
+// out the backpack.
+func (d *Display) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	type route struct{ method, path string }
+	var err error
+	switch (route{r.Method, r.URL.Path}) {
+	case route{"GET", "/api/state"}:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d.State())
+		return
+	case route{"POST", "/api/text"}:
+		var req struct {
+			Col, Row uint8
+			Text     string
+		}
+		if !decode(w, r, &req) {
+			return
+		}
+		if cols, rows := d.Size(); req.Col >= cols || req.Row >= rows {
+			http.Error(w, fmt.Sprintf("invalid request: col %d, row %d is outside the %dx%d display", req.Col, req.Row, cols, rows), http.StatusBadRequest)
+			return
+		}
+		d.SetText(req.Col, req.Row, req.Text)
+		err = d.Flush()
+	case route{"POST", "/api/brightness"}:
+		var req struct{ Value uint8 }
+		if !decode(w, r, &req) {
+			return
+		}
+		d.throttleSetting()
+		err = d.lcd.SetBrightness(req.Value)
+	case route{"POST", "/api/contrast"}:
+		var req struct{ Value uint8 }
+		if !decode(w, r, &req) {
+			return
+		}
+		d.throttleSetting()
+		err = d.lcd.SetContrast(req.Value)
+	case route{"POST", "/api/color"}:
+		var req struct{ R, G, B uint8 }
+		if !decode(w, r, &req) {
+			return
+		}
+		err = d.lcd.SetBG(req.R, req.G, req.B)
+	case route{"POST", "/api/backlight"}:
+		var req struct{ On bool }
+		if !decode(w, r, &req) {
+			return
+		}
+		err = d.lcd.SetOn(req.On)
+	case route{"DELETE", "/api/display"}:
+		d.Clear()
+		err = d.Flush()
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	d.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// throttleSetting sleeps as needed so that the API doesn't change the
+// brightness or contrast more often than MinBGInterval.
+func (d *Display) throttleSetting() {
+	d.mu.Lock()
+	wait := MinBGInterval - time.Since(d.lastSetting)
+	d.lastSetting = time.Now().Add(max(wait, 0))
+	d.mu.Unlock()
+	time.Sleep(wait)
+}
+
+// decode reads the JSON request body into v, replying with an error and
+// returning false if it's invalid.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
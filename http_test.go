@@ -0,0 +1,54 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// post sends body to the display's API at path and returns the status code.
+func post(d *Display, path, body string) int {
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest("POST", path, strings.NewReader(body)))
+	return rec.Code
+}
+
+func TestServeHTTP_Text(t *testing.T) {
+	lcd, _ := newMemLCD()
+	d := NewDisplay(lcd)
+	for _, test := range []struct {
+		body string
+		want int
+	}{
+		{`{"col": 0, "row": 1, "text": "hi"}`, http.StatusNoContent},
+		{`{"col": 15, "row": 0, "text": "hi"}`, http.StatusNoContent},
+		{`{"col": 0, "row": 2, "text": "hi"}`, http.StatusBadRequest},
+		{`{"col": 16, "row": 0, "text": "hi"}`, http.StatusBadRequest},
+		{`{"col": 0, "row": -1, "text": "hi"}`, http.StatusBadRequest},
+	} {
+		if got := post(d, "/api/text", test.body); got != test.want {
+			t.Errorf("POST /api/text %s replied %d, want %d", test.body, got, test.want)
+		}
+	}
+}
+
+func TestServeHTTP_SettingsAreRateLimited(t *testing.T) {
+	lcd, port := newMemLCD()
+	d := NewDisplay(lcd)
+	start := time.Now()
+	for _, path := range []string{"/api/brightness", "/api/contrast", "/api/brightness"} {
+		if got := post(d, path, `{"value": 100}`); got != http.StatusNoContent {
+			t.Fatalf("POST %s replied %d", path, got)
+		}
+	}
+	if d := time.Since(start); d < 2*MinBGInterval {
+		t.Errorf("3 settings changes took %v, want at least %v", d, 2*MinBGInterval)
+	}
+	want := cat(cmd(BRIGHTNESS, 100), cmd(CONTRAST, 100), cmd(BRIGHTNESS, 100))
+	if got := port.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package serial_lcd
+
+// Common status icons, ready to be registered with LCD.RegisterChar or
+// RegisterIcons.
+var (
+	IconWifi = MakeChar([8]string{
+		".....",
+		".***.",
+		"*...*",
+		"..*..",
+		".*.*.",
+		".....",
+		"..*..",
+		".....",
+	})
+	IconBattery = MakeChar([8]string{
+		".***.",
+		"*****",
+		"*...*",
+		"*...*",
+		"*****",
+		"*****",
+		"*****",
+		"*****",
+	})
+	IconPlay = MakeChar([8]string{
+		"*....",
+		"**...",
+		"***..",
+		"****.",
+		"***..",
+		"**...",
+		"*....",
+		".....",
+	})
+	IconPause = MakeChar([8]string{
+		".....",
+		"**.**",
+		"**.**",
+		"**.**",
+		"**.**",
+		"**.**",
+		"**.**",
+		".....",
+	})
+	IconStop = MakeChar([8]string{
+		".....",
+		".....",
+		"*****",
+		"*****",
+		"*****",
+		"*****",
+		"*****",
+		".....",
+	})
+	IconArrowUp = MakeChar([8]string{
+		"..*..",
+		".***.",
+		"*.*.*",
+		"..*..",
+		"..*..",
+		"..*..",
+		"..*..",
+		".....",
+	})
+	IconArrowDown = MakeChar([8]string{
+		"..*..",
+		"..*..",
+		"..*..",
+		"..*..",
+		"*.*.*",
+		".***.",
+		"..*..",
+		".....",
+	})
+	IconCheck = MakeChar([8]string{
+		".....",
+		"....*",
+		"...**",
+		"*.**.",
+		"***..",
+		".*...",
+		".....",
+		".....",
+	})
+	IconHeart = MakeChar([8]string{
+		".....",
+		".*.*.",
+		"*****",
+		"*****",
+		".***.",
+		"..*..",
+		".....",
+		".....",
+	})
+	IconBell = MakeChar([8]string{
+		"..*..",
+		".***.",
+		".***.",
+		".***.",
+		"*****",
+		".....",
+		"..*..",
+		".....",
+	})
+)
+
+// Icons maps names to the built-in icons, for use with RegisterIcons.
+var Icons = map[string]Char{
+	"wifi":       IconWifi,
+	"battery":    IconBattery,
+	"play":       IconPlay,
+	"pause":      IconPause,
+	"stop":       IconStop,
+	"arrow_up":   IconArrowUp,
+	"arrow_down": IconArrowDown,
+	"check":      IconCheck,
+	"heart":      IconHeart,
+	"bell":       IconBell,
+}
@@ -1,25 +1,87 @@
 // Package serial_lcd controls an Adafruit 16x2 serial-backpack LCD display (e.g. connected over USB).
 //
 // This package is specifically designed to work with the Adafruit serial
-// backpack LCD kit (http://www.adafruit.com/products/784).
+// backpack LCD kit (http://www.adafruit.com/products/784).  The commands it
+// sends are described in the backpack's command reference at
+// https://learn.adafruit.com/usb-plus-serial-backpack/command-reference.
 //
 // Typical usage is (with all error handling omitted):
 //
-//   lcd, _ := serial_lcd.Open("COM2", 9600) // or "/dev/tty.usbmodem1451"
-//   defer lcd.Close()
-//   lcd.SetSize(16,2)
-//   lcd.SetBrightness(255)
-//   lcd.SetContrast(200)
-//   lcd.SetCursor(UNDERLINE_CURSOR_OFF, BLOCK_CURSOR_OFF)
-//   lcd.SetBG(0,0,255) // R,G,B
-//   lcd.Clear()
-//   lcd.Home()
-//   fmt.Fprint(lcd, "Hi there!")
+//	lcd, _ := serial_lcd.Open("COM2", 9600) // or "/dev/tty.usbmodem1451"
+//	defer lcd.Close()
+//	lcd.SetSize(16,2)
+//	lcd.SetBrightness(255)
+//	lcd.SetContrast(200)
+//	lcd.SetCursorVisible(false, false) // underline, block
+//	lcd.SetBG(0,0,255) // R,G,B
+//	lcd.Clear()
+//	lcd.Home()
+//	fmt.Fprint(lcd, "Hi there!")
 //
+// # Other connections
+//
+// To use a connection other than a local serial port, such as a network
+// bridge, wrap it with New.  Older versions of this package had no New and
+// were used as LCD{rwc}, which no longer compiles because LCD has other,
+// unexported fields now.  LCD{ReadWriteCloser: rwc} does compile and works,
+// but New also gives ErrClosed after Close, so prefer it.
+//
+// # LCD and Display
+//
+// An LCD sends each call straight to the backpack.  Text is written with
+// fmt.Fprint or Write at the cursor, and positions start at 1,1 as in the
+// backpack's protocol.  Commands can be collected with Batch and sent in a
+// single write.
+//
+// A Display keeps a copy of the screen in memory instead.  Text is drawn
+// anywhere with rows and columns counting from 0, and Flush sends only the
+// characters that changed.  This suits screens that are redrawn often, and
+// Compositor, Router and the HTTP API build on it.
+//
+// # Custom characters
+//
+// The display has room for 8 custom characters at a time, in slots 0-7, which
+// are shown by writing the slot's byte.  Create them from a picture with
+// MakeChar and load them with CreateCustomChar, or let RegisterChar pick free
+// slots.  The backpack can also save 4 banks of 8 characters to its EEPROM, see
+// LoadCharBank.
+//
+// # EEPROM
+//
+// The backpack saves the size, contrast, brightness, background color and
+// splash screen to its EEPROM, so they survive a power cycle and don't need to
+// be set every time.  EEPROM wears out after many writes, so avoid setting
+// these in a loop; SetBG slows itself down to at most one change every
+// MinBGInterval.  Text, the cursor and the custom characters aren't saved.
+//
+// # Speed
+//
+// The backpack runs at 9600 baud unless configured otherwise, which is about
+// 1000 characters a second.  Sending faster than the backpack can process can
+// garble the display, so SetMinInterval can space out writes, and LCD.Async can
+// queue them so that slow writes don't hold up the caller.
+//
+// # Testing
+//
+// NullLCD returns an LCD that discards everything, for running without a
+// display attached.  A Display over it keeps its screen in memory as usual, so
+// tests can check what would be shown with Display.State, and Tee can log the
+// commands that would be sent.
 package serial_lcd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"log"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/tarm/goserial"
 )
@@ -27,28 +89,470 @@ import (
 type UnderlineCursorState uint8
 type BlockCursorState uint8
 
-type LCD struct{ io.ReadWriteCloser }
+type LCD struct {
+	io.ReadWriteCloser
+	st *state    // see state
+	vp *viewport // limits drawing to part of the display, see Viewport
+}
+
+// adopted holds the state of LCDs that were made without New or Open, by
+// setting only the connection as in LCD{ReadWriteCloser: rwc}, keyed by the
+// connection.
+var adopted sync.Map
+
+// sharedState is the state of adopted LCDs whose connection can't be a map
+// key.
+var sharedState = newState()
+
+// state returns the LCD's state.  An LCD made with New or Open carries its
+// own; one made from just a connection, which was how to wrap a connection
+// before New existed, gets one on first use that's kept for as long as the
+// program runs.
+func (l LCD) state() *state {
+	if l.st != nil {
+		return l.st
+	}
+	if l.ReadWriteCloser == nil || !reflect.TypeOf(l.ReadWriteCloser).Comparable() {
+		return sharedState
+	}
+	s, _ := adopted.LoadOrStore(l.ReadWriteCloser, newState())
+	return s.(*state)
+}
+
+// state tracks what has been sent to the LCD.  It's shared by all copies of an
+// LCD value so that the value receivers below can keep it up to date.
+type state struct {
+	mu sync.Mutex
+	tracked
+
+	quirks    FirmwareQuirks
+	logger    *log.Logger
+	lastBG    time.Time // when SetBG was last called
+	warnedBG  bool      // whether SetBG's rate limit has been logged
+	noLinePad bool      // see SetLineWrapPadding
+
+	minInterval time.Duration // see SetMinInterval
+	nextWrite   time.Time     // when the next write may be sent
+
+	closed         bool // whether the connection has been closed
+	looseTemplates bool // see SetTemplateStrict
+
+	// pendingClear is set when a Clear should be sent along with the next
+	// write, see WithClearOnOpen.
+	pendingClear bool
+
+	blinkHz   float64            // backlight blink rate for BacklightBlinking
+	stopBlink context.CancelFunc // stops the backlight blinking, if it is
+	blinkDone chan struct{}      // closed when the blinking has stopped
+
+	query      sync.Mutex // held while waiting for a reply, see read
+	readerOnce sync.Once  // starts the reader, see replies
+	replyCh    chan byte  // bytes received from the backpack
+	readErr    error      // why the reader stopped, once replyCh is closed
+}
+
+// tracked is the part of the state that follows from what's been sent to the
+// display, which a failed Batch rolls back.
+type tracked struct {
+	brightness uint8
+	contrast   uint8
+	inverted   bool
+	autoscroll bool
+	cols, rows uint8        // display size
+	col, row   uint8        // cursor position, starting at 1,1
+	bg         Color        // background color
+	off        bool         // whether the backlight is off
+	bank       int          // custom character bank loaded, or -1 if unknown
+	chars      CharRegistry // custom characters added with RegisterChar
+}
+
+// New returns an LCD that communicates over an already-open connection to the
+// backpack.  Most users want Open instead.
+func New(rwc io.ReadWriteCloser) LCD {
+	s := newState()
+	return LCD{closeTracker{rwc, s}, s, nil}
+}
+
+// newState returns the state of a newly connected LCD, with the backpack's
+// default settings.
+func newState() *state {
+	return &state{
+		tracked: tracked{brightness: 255, contrast: 200, bg: Color{255, 255, 255}, bank: -1, cols: 16, rows: 2, col: 1, row: 1},
+		blinkHz: 1,
+	}
+}
+
+// ErrClosed is returned when using an LCD that has been closed.
+var ErrClosed = errors.New("serial_lcd: LCD is closed")
+
+// closeTracker returns ErrClosed for anything done after Close, instead of
+// whatever the connection returns for a closed file.
+type closeTracker struct {
+	io.ReadWriteCloser
+	s *state
+}
+
+func (c closeTracker) isClosed() bool {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	return c.s.closed
+}
+
+func (c closeTracker) Read(p []byte) (int, error) {
+	if c.isClosed() {
+		return 0, ErrClosed
+	}
+	return c.ReadWriteCloser.Read(p)
+}
+
+func (c closeTracker) Write(p []byte) (int, error) {
+	if c.isClosed() {
+		return 0, ErrClosed
+	}
+	return c.ReadWriteCloser.Write(p)
+}
+
+func (c closeTracker) Close() error {
+	c.s.mu.Lock()
+	closed := c.s.closed
+	c.s.closed = true
+	c.s.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	return c.ReadWriteCloser.Close()
+}
+
+func (c closeTracker) Drain() error {
+	if d, ok := c.ReadWriteCloser.(drainer); ok {
+		return d.Drain()
+	}
+	return nil
+}
+
+// track calls update with the state locked if err is nil, and returns err.
+func (l LCD) track(err error, update func(s *state)) error {
+	if err == nil {
+		l.state().mu.Lock()
+		update(l.state())
+		l.state().mu.Unlock()
+	}
+	return err
+}
+
+// advance moves the tracked cursor forward n characters, wrapping at the end
+// of each row the same way the backpack does.
+func (s *state) advance(n int) {
+	col := int(s.col) + n
+	for col > int(s.cols) && s.cols > 0 {
+		col -= int(s.cols)
+		if s.row < s.rows {
+			s.row++
+		} else if !s.autoscroll {
+			s.row = 1
+		}
+	}
+	s.col = uint8(col)
+}
+
+// size returns the tracked size of the display, or of the viewport.
+func (l LCD) size() (cols, rows uint8) {
+	if l.vp != nil {
+		return l.vp.cols, l.vp.rows
+	}
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	return l.state().cols, l.state().rows
+}
+
+// CursorPos returns the position of the cursor, starting at 1,1, as tracked
+// from the commands and text sent to the LCD.  For a viewport the position is
+// relative to the viewport's top left corner.
+func (l LCD) CursorPos() (col, row uint8) {
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	if l.vp != nil {
+		return l.vp.cursor()
+	}
+	return l.state().col, l.state().row
+}
+
+// Open connects to the LCD on the given serial port.  By default a single
+// attempt is made; see WithRetry to keep trying while the device appears.  If
+// every attempt fails, the returned error joins the errors from each attempt.
+//
+// The port should be a device path like "/dev/ttyUSB0" or a Windows port name
+// like "COM3".  Windows ports above COM9 are automatically given the \\.\
+// prefix they require.
+func Open(port string, baud int, opts ...Option) (LCD, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	port, err := portName(port)
+	if err != nil {
+		return LCD{}, err
+	}
+	var errs []error
+	for i := 1; ; i++ {
+		s, err := openPort(port, baud)
+		if err == nil {
+			l := New(s)
+			l.state().logger = o.logger
+			if o.selfTest {
+				if err := l.selfTest(); err != nil {
+					l.Close()
+					return LCD{}, fmt.Errorf("serial_lcd: self-test on %s failed: %w", port, err)
+				}
+			}
+			l.state().pendingClear = o.clearOnOpen
+			return l, nil
+		}
+		errs = append(errs, err)
+		o.logf("serial_lcd: opening %s failed (attempt %d): %v", port, i, err)
+		if o.attempts >= 0 && i >= o.attempts {
+			break
+		}
+		time.Sleep(o.backoff)
+	}
+	if len(errs) == 1 {
+		return LCD{}, errs[0]
+	}
+	return LCD{}, errors.Join(errs...)
+}
+
+// openPort opens a serial port.  Tests replace it to connect Open to an
+// in-memory port.
+var openPort = func(name string, baud int) (io.ReadWriteCloser, error) {
+	return serial.OpenPort(&serial.Config{Name: name, Baud: baud})
+}
+
+var windowsPort = regexp.MustCompile(`^(?i)COM([0-9]+)$`)
 
-func Open(port string, baud int) (LCD, error) {
-	s, err := serial.OpenPort(&serial.Config{Name: port, Baud: baud})
-	return LCD{s}, err
+// portName validates a serial port name and converts Windows port names that
+// need it to the \\.\COMxx form.
+func portName(name string) (string, error) {
+	if m := windowsPort.FindStringSubmatch(name); m != nil {
+		if n, _ := strconv.Atoi(m[1]); n > 9 {
+			return `\\.\` + name, nil
+		}
+		return name, nil
+	}
+	if strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\\.\`) {
+		return name, nil
+	}
+	return "", fmt.Errorf("serial_lcd: %q is not a serial port name (expected something like COM3 or /dev/ttyUSB0)", name)
+}
+
+// DrainTimeout is how long Drain waits for pending writes before giving up.
+var DrainTimeout = 5 * time.Second
+
+// ErrDrainTimeout is returned by Drain when pending writes don't finish within
+// DrainTimeout.
+var ErrDrainTimeout = errors.New("serial_lcd: timed out draining pending writes")
+
+// drainer is implemented by connections that buffer or queue writes.
+type drainer interface{ Drain() error }
+
+// Drain blocks until all pending writes have been sent, or returns
+// ErrDrainTimeout if that takes longer than DrainTimeout.  Writing directly to
+// a serial port doesn't queue anything, so this only waits when the LCD's
+// connection buffers writes.  Calling it before exiting (e.g. with
+// defer lcd.Drain()) ensures the last message is fully displayed.
+func (l LCD) Drain() error {
+	d, ok := l.ReadWriteCloser.(drainer)
+	if !ok {
+		return nil
+	}
+	done := make(chan error, 1)
+	go func() { done <- d.Drain() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(DrainTimeout):
+		return ErrDrainTimeout
+	}
+}
+
+// GracefulClose clears the display, turns off the backlight and closes the
+// connection, so that the LCD goes dark when the program exits instead of
+// freezing on its last message.  The connection is closed even if blanking the
+// display fails; the first error is returned.
+func (l LCD) GracefulClose() error {
+	err := l.Clear()
+	for _, step := range []func() error{l.Off, l.Drain, l.Close} {
+		if e := step(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// CloseMessageDelay is how long CloseWithMessage shows its message.
+var CloseMessageDelay = time.Second
+
+// CloseWithMessage shows a final message such as "Shutting down..." for
+// CloseMessageDelay, then blanks the display and closes it like GracefulClose.
+func (l LCD) CloseWithMessage(msg string) error {
+	err := l.Clear()
+	if err == nil {
+		err = dropN(io.WriteString(l, msg))
+	}
+	if err == nil {
+		time.Sleep(CloseMessageDelay)
+	}
+	if e := l.GracefulClose(); err == nil {
+		err = e
+	}
+	return err
 }
 
 // dropN ignores the number of bytes written and just returns the error.
 func dropN(n int, e error) error { return e }
 
-// Raw writes a series of raw bytes to the LCD.
-func (l LCD) Raw(bytes ...byte) error { return dropN(l.Write(bytes)) }
+// Raw writes a series of raw bytes to the LCD.  See WriteBytes.
+func (l LCD) Raw(bytes ...byte) error { return l.WriteBytes(bytes) }
+
+// WriteBytes writes raw bytes to the LCD.  Data that doesn't start with the
+// COMMAND byte is assumed to be text.
+//
+// The backpack never replies to commands or text, so a write that is
+// corrupted on the way (e.g. by a noisy USB cable) can't be detected or
+// retried.  An error only means the bytes couldn't be handed to the serial
+// port.
+func (l LCD) WriteBytes(data []byte) error {
+	if l.vp != nil && len(data) > 0 && data[0] != COMMAND {
+		return dropN(l.vp.write(l, data))
+	}
+	err := dropN(l.send(data))
+	if len(data) > 0 && data[0] != COMMAND {
+		return l.track(err, func(s *state) { s.advance(len(data)) })
+	}
+	return err
+}
+
+// send writes p to the connection, first sending any pending Clear.
+func (l LCD) send(p []byte) (int, error) {
+	l.state().mu.Lock()
+	clear := l.state().pendingClear
+	l.state().pendingClear = false
+	var wait time.Duration
+	if _, batching := l.ReadWriteCloser.(*batch); !batching {
+		wait = time.Until(l.state().nextWrite)
+		l.state().nextWrite = time.Now().Add(max(wait, 0) + l.state().minInterval)
+	}
+	l.state().mu.Unlock()
+	time.Sleep(wait)
+	if !clear {
+		return l.ReadWriteCloser.Write(p)
+	}
+	n, err := l.ReadWriteCloser.Write(append([]byte{COMMAND, CLEAR}, p...))
+	if err != nil {
+		l.state().mu.Lock()
+		l.state().pendingClear = true
+		l.state().mu.Unlock()
+	}
+	return max(n-2, 0), err
+}
+
+// Write writes text to the display at the cursor.  It implements io.Writer so
+// that fmt.Fprint(lcd, ...) works.  Unlike Raw, the text is subject to display
+// modes such as SetInverted.  In a viewport, text wraps at the viewport's edge.
+func (l LCD) Write(p []byte) (int, error) {
+	if l.isInverted() {
+		p = invert(p)
+	}
+	if l.vp != nil {
+		return l.vp.write(l, p)
+	}
+	return l.write(p)
+}
+
+// write sends text and advances the tracked cursor past it.
+func (l LCD) write(p []byte) (int, error) {
+	n, err := l.send(p)
+	l.state().mu.Lock()
+	l.state().advance(n)
+	l.state().mu.Unlock()
+	return n, err
+}
+
+// Command sends a backpack command: the COMMAND byte followed by cmd and its
+// args.  This is an escape hatch for commands that don't have a method yet.
+func (l LCD) Command(cmd byte, args ...byte) error {
+	return l.WriteBytes(append([]byte{COMMAND, cmd}, args...))
+}
+
+// SetMinInterval makes every write to the LCD wait until at least d after the
+// previous one, as a guard against update loops that send faster than the
+// backpack can keep up, garbling the display.  Each command or piece of text
+// is a separate write, except within Batch.  A d of 0, the default, turns it
+// off.
+func (l LCD) SetMinInterval(d time.Duration) {
+	l.state().mu.Lock()
+	l.state().minInterval = d
+	l.state().mu.Unlock()
+}
+
+// MinBGInterval is the shortest time allowed between SetBG calls.  It guards
+// against runaway loops rather than making rapid changes safe: changing the
+// color this often wears the EEPROM out in about three hours.
+var MinBGInterval = 100 * time.Millisecond
 
 // SetBG sets the background color.  The RGB values should each be 0-255.
-func (l LCD) SetBG(r, g, b uint8) error { return l.Raw(COMMAND, SET_RGB_BACKLIGHT_COLOR, r, g, b) }
+//
+// The backpack saves the color to its EEPROM every time, and EEPROM wears out
+// after around 100,000 writes.  Rapidly changing colors, such as in a color
+// cycling animation, can wear it out within hours, so change it only when
+// something happens, or use AnimateBackground.  To limit the damage, SetBG
+// sleeps as needed so that it's never called more often than MinBGInterval,
+// and logs a warning the first time that happens.
+func (l LCD) SetBG(r, g, b uint8) error {
+	l.state().mu.Lock()
+	wait := MinBGInterval - time.Since(l.state().lastBG)
+	warn := wait > 0 && !l.state().warnedBG
+	l.state().warnedBG = l.state().warnedBG || wait > 0
+	l.state().lastBG = time.Now().Add(max(wait, 0))
+	l.state().mu.Unlock()
+	if warn {
+		l.logf("serial_lcd: SetBG called more often than every %v, slowing down to protect the EEPROM", MinBGInterval)
+	}
+	time.Sleep(wait)
+	return l.track(l.Command(SET_RGB_BACKLIGHT_COLOR, r, g, b), func(s *state) { s.bg = Color{r, g, b} })
+}
+
+// logf logs a message if a logger was configured with WithLogger.
+func (l LCD) logf(format string, args ...interface{}) {
+	l.state().mu.Lock()
+	logger := l.state().logger
+	l.state().mu.Unlock()
+	if logger != nil {
+		logger.Printf(format, args...)
+	}
+}
 
 // Off turns the LCD backlight off.
-func (l LCD) Off() error { return l.Raw(COMMAND, BACKLIGHT_OFF) }
+func (l LCD) Off() error {
+	return l.track(l.Command(BACKLIGHT_OFF), func(s *state) { s.off = true })
+}
 
 // On turns the LCD backlight on.
-func (l LCD) On() error { return l.Raw(COMMAND, BACKLIGHT_ON, 0) }
+//
+// The documented firmware expects BACKLIGHT_ON to be followed by one argument
+// (a Matrix Orbital style "minutes until off" value that the Adafruit firmware
+// ignores), so On sends a 0 after the command.  Firmware that takes no argument
+// would display that 0 as custom character 0; use SetFirmwareQuirks with
+// QuirkNoBacklightOnArg for such firmware.
+func (l LCD) On() error {
+	args := []byte{0}
+	if l.quirks()&QuirkNoBacklightOnArg != 0 {
+		args = nil
+	}
+	return l.track(l.Command(BACKLIGHT_ON, args...), func(s *state) { s.off = false })
+}
 
+// SetOn turns the backlight on or off, for toggles such as a checkbox or a
+// setting read from a form.
 func (l LCD) SetOn(on bool) error {
 	if on {
 		return l.On()
@@ -56,40 +560,278 @@ func (l LCD) SetOn(on bool) error {
 	return l.Off()
 }
 
+// FirmwareQuirks describes differences between backpack firmware revisions
+// that change how commands must be sent.
+type FirmwareQuirks uint8
+
+const (
+	// QuirkNoBacklightOnArg is for firmware where BACKLIGHT_ON doesn't take an
+	// argument, so On sends the command by itself like Off does.
+	QuirkNoBacklightOnArg FirmwareQuirks = 1 << iota
+)
+
+// SetFirmwareQuirks sets the firmware differences to accommodate.  The default
+// of no quirks matches the documented firmware.
+func (l LCD) SetFirmwareQuirks(q FirmwareQuirks) {
+	l.state().mu.Lock()
+	l.state().quirks = q
+	l.state().mu.Unlock()
+}
+
+func (l LCD) quirks() FirmwareQuirks {
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	return l.state().quirks
+}
+
 // SetBrightness sets the LCD backlight brightness.  0-255 where 255 is the brightest.
-func (l LCD) SetBrightness(b uint8) error { return l.Raw(COMMAND, BRIGHTNESS, b) }
+// Like the background color, the brightness is saved to the backpack's EEPROM
+// every time, so it shouldn't be changed continuously.
+func (l LCD) SetBrightness(b uint8) error {
+	return l.track(l.Command(BRIGHTNESS, b), func(s *state) { s.brightness = b })
+}
+
+// Brightness returns the most recently set backlight brightness.  Until
+// SetBrightness is called this assumes the backpack default of 255.
+func (l LCD) Brightness() uint8 {
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	return l.state().brightness
+}
+
+// fadeStep is the shortest delay between brightness updates when fading.
+const fadeStep = 20 * time.Millisecond
+
+// fadeSteps is the most brightness updates made by a fade.  Each one is an
+// EEPROM write.
+const fadeSteps = 8
+
+// SetBrightnessSmooth ramps the backlight brightness from its current value to
+// target over the given duration.  The ramp is gamma corrected so that it looks
+// even to the eye instead of rushing through the dim end.
+//
+// Every step is saved to the backpack's EEPROM, which wears out after around
+// 100,000 writes, so the ramp is made of at most 8 steps and looks stepped
+// rather than smooth.  Even so, fading once a minute wears the EEPROM out in
+// about nine days; keep fades for occasional transitions such as waking up.
+func (l LCD) SetBrightnessSmooth(target uint8, duration time.Duration) error {
+	from := l.Brightness()
+	steps := max(min(int(duration/fadeStep), fadeSteps), 1)
+	start, end := perceived(from), perceived(target)
+	last := from
+	for i := 1; i <= steps; i++ {
+		b := fromPerceived(start + (end-start)*float64(i)/float64(steps))
+		if i == steps {
+			b = target
+		}
+		if b != last {
+			if err := l.SetBrightness(b); err != nil {
+				return err
+			}
+			last = b
+		}
+		if i < steps {
+			time.Sleep(duration / time.Duration(steps))
+		}
+	}
+	return nil
+}
+
+// gamma approximates the eye's response to backlight brightness.
+const gamma = 2.2
+
+// perceived converts a brightness value to its perceived level in 0-1.
+func perceived(b uint8) float64 { return math.Pow(float64(b)/255, 1/gamma) }
+
+// fromPerceived converts a perceived level in 0-1 back to a brightness value.
+func fromPerceived(p float64) uint8 { return uint8(math.Round(255 * math.Pow(p, gamma))) }
+
+// autoLevels is how many brightness levels AutoBrightness picks from, besides
+// off.
+const autoLevels = 8
+
+// AutoBrightness sets the backlight brightness to suit the ambient light, as
+// read from a light sensor in lux.  Readings from minLux to maxLux map to
+// brightness from 0 to 255, gamma corrected like SetBrightnessSmooth so that
+// equal changes in light give changes that look equal.  Readings outside of the
+// range are clamped to it.
+//
+// The brightness is saved to the backpack's EEPROM, so to keep a noisy sensor
+// from wearing it out, the range is split into 8 levels and nothing is sent
+// unless the reading moves to a different level.  Readings near a boundary can
+// still flip between two levels, so smooth or space out the readings too,
+// ideally to no more than one every few minutes.
+func (l LCD) AutoBrightness(lux, minLux, maxLux float64) error {
+	if !(maxLux > minLux) {
+		return fmt.Errorf("serial_lcd: invalid lux range %v-%v", minLux, maxLux)
+	}
+	p := math.Max(0, math.Min((lux-minLux)/(maxLux-minLux), 1))
+	if math.IsNaN(p) {
+		return fmt.Errorf("serial_lcd: invalid lux reading %v", lux)
+	}
+	b := fromPerceived(math.Round(p*autoLevels) / autoLevels)
+	if b == l.Brightness() {
+		return nil
+	}
+	return l.SetBrightness(b)
+}
 
 // SetContrast sets the LCD backlight contrast. 0-255, usually 200 is a nice value.
-func (l LCD) SetContrast(c uint8) error { return l.Raw(COMMAND, CONTRAST, c) }
+func (l LCD) SetContrast(c uint8) error {
+	return l.track(l.Command(CONTRAST, c), func(s *state) { s.contrast = c })
+}
+
+// Contrast returns the most recently set contrast.  Until SetContrast is
+// called this assumes 200.
+func (l LCD) Contrast() uint8 {
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	return l.state().contrast
+}
+
+// Percent is a level from 0 to 100, such as a brightness, for settings that
+// the backpack takes as a byte from 0 to 255.  Values over 100 count as 100.
+type Percent uint8
 
-// Autoscrolls determines how the LCD handles more text than fits on the
+// ToByte converts p to the backpack's 0-255 range, mapping 100 to 255.
+func (p Percent) ToByte() uint8 {
+	return uint8(math.Round(float64(min(p, 100)) * 255 / 100))
+}
+
+// PercentFromByte converts a value in the backpack's 0-255 range to a Percent,
+// mapping 255 to 100.
+func PercentFromByte(b uint8) Percent { return Percent(math.Round(float64(b) * 100 / 255)) }
+
+// SetBrightnessPct is like SetBrightness, but takes a percentage.  Note that
+// the display is hard to read below about 40%.
+func (l LCD) SetBrightnessPct(pct Percent) error { return l.SetBrightness(pct.ToByte()) }
+
+// SetContrastPct is like SetContrast, but takes a percentage.
+func (l LCD) SetContrastPct(pct Percent) error { return l.SetContrast(pct.ToByte()) }
+
+// SetAutoscroll determines how the LCD handles more text than fits on the
 // display.  When on, if more text is received than fits it will immediately be
 // scrolled so that the newest text is always at the bottom.  When off, as more
 // text is received the display wraps around to the beginning.
 func (l LCD) SetAutoscroll(on bool) error {
+	cmd := byte(AUTOSCROLL_OFF)
 	if on {
-		return l.Raw(COMMAND, AUTOSCROLL_ON)
-	} else {
-		return l.Raw(COMMAND, AUTOSCROLL_OFF)
+		cmd = AUTOSCROLL_ON
+	}
+	return l.track(l.Command(cmd), func(s *state) { s.autoscroll = on })
+}
+func (l LCD) SetSize(cols, rows uint8) error {
+	return l.track(l.Command(SET_LCD_SIZE, cols, rows), func(s *state) { s.cols, s.rows = cols, rows })
+}
+func (l LCD) Clear() error {
+	if l.vp != nil {
+		return l.vp.clear(l)
+	}
+	return l.track(l.Command(CLEAR), home)
+}
+
+// home moves the tracked cursor to 1,1.
+func home(s *state) { s.col, s.row = 1, 1 }
+
+// SetCursorVisible shows or hides the underline and blinking block cursors.
+// Both can be shown at once.
+func (l LCD) SetCursorVisible(underline, block bool) error {
+	u, b := UNDERLINE_CURSOR_OFF, BLOCK_CURSOR_OFF
+	if underline {
+		u = UNDERLINE_CURSOR_ON
 	}
+	if block {
+		b = BLOCK_CURSOR_ON
+	}
+	return l.SetCursor(u, b)
 }
-func (l LCD) SetSize(cols, rows uint8) error { return l.Raw(COMMAND, SET_LCD_SIZE, cols, rows) }
-func (l LCD) Clear() error                   { return l.Raw(COMMAND, CLEAR) }
 
+// SetCursor is like SetCursorVisible, but takes the command constants.
 func (l LCD) SetCursor(u UnderlineCursorState, b BlockCursorState) error {
-	return l.Raw(COMMAND, byte(u), COMMAND, byte(b))
+	return l.WriteBytes([]byte{COMMAND, byte(u), COMMAND, byte(b)})
 }
 
 // Move the cursor home (to 1,1).
-func (l LCD) Home() error { return l.Raw(COMMAND, GO_HOME) }
+func (l LCD) Home() error {
+	if l.vp != nil {
+		return l.MoveTo(1, 1)
+	}
+	return l.track(l.Command(GO_HOME), home)
+}
 
 // Set the cursor position.  Row/col number starts at 1,1.
-func (l LCD) MoveTo(col, row uint8) error { return l.Raw(COMMAND, SET_CURSOR_POSITION, col, row) }
-func (l LCD) MoveForward() error          { return l.Raw(COMMAND, CURSOR_FORWARD) }
-func (l LCD) MoveBack() error             { return l.Raw(COMMAND, CURSOR_BACK) }
+func (l LCD) MoveTo(col, row uint8) error {
+	if l.vp != nil {
+		return l.vp.moveTo(l, col, row)
+	}
+	return l.track(l.Command(SET_CURSOR_POSITION, col, row), func(s *state) { s.col, s.row = col, row })
+}
+func (l LCD) MoveForward() error {
+	return l.track(l.Command(CURSOR_FORWARD), func(s *state) { s.step(1) })
+}
+func (l LCD) MoveBack() error {
+	return l.track(l.Command(CURSOR_BACK), func(s *state) { s.step(-1) })
+}
 
+// step moves the tracked cursor one position forward or back, wrapping between
+// the first and last positions of the display.
+func (s *state) step(dir int) {
+	pos := (int(s.row)-1)*int(s.cols) + int(s.col) - 1 + dir
+	n := int(s.cols) * int(s.rows)
+	if n == 0 {
+		return
+	}
+	pos = (pos%n + n) % n
+	s.col, s.row = uint8(pos%int(s.cols))+1, uint8(pos/int(s.cols))+1
+}
+
+// CreateCustomChar defines custom character spot (0-7), which is then shown
+// wherever the byte spot is written.  For example, to show a heart made with
+// MakeChar:
+//
+//	lcd.CreateCustomChar(1, heart)
+//	fmt.Fprint(lcd, "I ")
+//	lcd.PrintChar(1)
+//	fmt.Fprint(lcd, " Go")
+//
+// Spot 0 is shown by writing a NUL byte, so text containing it can be cut short
+// by anything that treats it as a C string.  Printing it with PrintChar, or
+// using another spot, avoids putting the NUL in strings at all; see also
+// SetAvoidCharSlotZero.
 func (l LCD) CreateCustomChar(spot uint8, c Char) error {
-	return l.Raw(append([]byte{COMMAND, CREATE_CUSTOM_CHARACTER, spot}, c[:]...)...)
+	err := l.Command(CREATE_CUSTOM_CHARACTER, append([]byte{spot}, c[:]...)...)
+	return l.track(err, func(s *state) { s.bank = -1 })
+}
+
+// NumCharBanks is the number of EEPROM banks of custom characters.
+const NumCharBanks = 4
+
+// LoadCharBank replaces all of the custom characters with the ones saved in an
+// EEPROM bank (0-3).
+func (l LCD) LoadCharBank(bank uint8) error {
+	if bank >= NumCharBanks {
+		return fmt.Errorf("serial_lcd: invalid custom character bank %d", bank)
+	}
+	err := l.Command(LOAD_CUSTOM_CHARACTERS_FROM_EEPROM_BANK, bank)
+	return l.track(err, func(s *state) { s.bank = int(bank) })
+}
+
+// PrintBankChar writes the custom character in slot of an EEPROM bank at
+// col,row, first loading the bank unless it's already loaded.  Loading a bank
+// changes every custom character already on the screen too.
+func (l LCD) PrintBankChar(bank, slot, col, row uint8) error {
+	if err := checkSlot(slot); err != nil {
+		return err
+	}
+	l.state().mu.Lock()
+	loaded := l.state().bank == int(bank)
+	l.state().mu.Unlock()
+	if !loaded {
+		if err := l.LoadCharBank(bank); err != nil {
+			return err
+		}
+	}
+	return l.PrintCharAt(col, row, slot)
 }
 
 // Characters are 5x8 pixels.  The first 5 bits of each byte defines the pixels
@@ -113,17 +855,34 @@ type Char [8]byte
 //   })
 //   lcd.CreateCustomChar(0, heart)
 //
+// Only the first 5 characters of each line are used, and a shorter line is
+// padded on the right with off pixels.  Char.String draws the glyph back in
+// the same form, so MakeChar(art).String() gives back art, joined with
+// newlines, for any art drawn with '*' and '.' in lines of 5 characters.
 func MakeChar(lines [8]string) Char {
+	return MakeCharFromStrings(lines[:])
+}
+
+// MakeCharFromStrings is a more forgiving version of MakeChar for glyphs that
+// are generated by code or not fully designed yet.  It accepts up to 8 lines
+// and treats missing rows as all off.  Only the first 5 characters of each line
+// are used and a line shorter than that is padded on the right with off pixels.
+func MakeCharFromStrings(lines []string) Char {
 	var charDef Char
-	for i, line := range lines {
+	for i := 0; i < len(lines) && i < len(charDef); i++ {
 		var pixels byte
-		for _, c := range line {
+		n := 0
+		for _, c := range lines[i] {
+			if n == 5 {
+				break
+			}
 			pixels = pixels << 1
 			if c != '.' && c != ' ' {
 				pixels |= 1
 			}
+			n++
 		}
-		charDef[i] = pixels
+		charDef[i] = pixels << uint(5-n)
 	}
 	return charDef
 }
@@ -134,7 +893,7 @@ const (
 
 	// ---------------------------------------------------------------
 	// Basic commands:
-	BACKLIGHT_ON  = 0x42 // Turns the backlight on.  expect extra arg that is ignored.
+	BACKLIGHT_ON  = 0x42 // Turns the backlight on.  expect extra arg that is ignored (see QuirkNoBacklightOnArg).
 	BACKLIGHT_OFF = 0x46 // Turns the backlight off.
 	BRIGHTNESS    = 0x99 // Set brightness: expects arg for brightness 0-255
 	CONTRAST      = 0x91 // Set contrast: expects arg for contrast 0-255
@@ -153,8 +912,15 @@ const (
 	// after sending this command, write up to 32 characters (for 16x2) or up to
 	// 80 characters (for 20x4) that will appear as the splash screen during
 	// startup. If you don't want a splash screen, write a bunch of spaces.
+	// The firmware has no separate command to turn the splash screen on or
+	// off; setting it to spaces is the only way to get a blank boot.
 	SET_STARTUP_SPLASH = 0x40
 
+	// Requests the firmware version, which is sent back as a single byte
+	// with the major version in the high nibble.  This is a Matrix Orbital
+	// command that the Adafruit firmware doesn't implement.
+	READ_VERSION = 0x36
+
 	// ---------------------------------------------------------------
 	// Moving and changing the cursor:
 
@@ -180,6 +946,11 @@ const (
 
 	// ---------------------------------------------------------------
 	// RGB Backlight and LCD size
+	//
+	// Several of these settings are saved to the backpack's EEPROM.  The
+	// firmware has no commands to read or write the EEPROM directly, so these
+	// commands are the only way to change what's stored there, and nothing
+	// can be read back.
 
 	// Sets the backlight to the red, green and blue component colors. The
 	// values of can range from 0 to 255 (one byte). This is saved to EEPROM.
@@ -23,13 +23,11 @@ func main() {
 	lcd.Clear()
 	lcd.On()
 
-	lcd.SetCursor(serial_lcd.UNDERLINE_CURSOR_OFF, serial_lcd.BLOCK_CURSOR_OFF)
+	lcd.SetCursorVisible(false, false)
 	lcd.MoveTo(8, 2)
 	fmt.Fprint(lcd, "xyz")
 	delay(1000)
 
-	go loop(lcd)
-
 	for i := 0; i < 100; i++ {
 		x := uint8(i % 16)
 		y := uint8((i / 16) % 2)
@@ -43,22 +41,15 @@ func main() {
 	setup(lcd)
 
 	delay(3000)
-
-	lcd.SetBG(100, 0, 100)
 }
 
 func setup(lcd serial_lcd.LCD) {
-	lcd.SetSize(16, 2)
-	delay(10)
-
-	lcd.SetContrast(200)
-	delay(10)
-
-	lcd.SetBrightness(255)
-	delay(10)
+	// The backpack saves the background color to EEPROM every time it's set,
+	// so the demo sets it once here rather than cycling through colors.
+	lcd.Configure(16, 2, 255, 200, serial_lcd.Color{R: 100, G: 0, B: 100})
 
 	// turn off cursors
-	lcd.SetCursor(serial_lcd.UNDERLINE_CURSOR_OFF, serial_lcd.BLOCK_CURSOR_OFF)
+	lcd.SetCursorVisible(false, false)
 	delay(10)
 
 	// create a custom character
@@ -83,28 +74,11 @@ func setup(lcd serial_lcd.LCD) {
 	lcd.Home()
 	delay(10) // we suggest putting delays after each command
 
-	fmt.Fprint(lcd, " We \x00 Arduino!")
+	fmt.Fprint(lcd, " We ")
+	lcd.PrintChar(0)
+	fmt.Fprint(lcd, " Arduino!")
 	fmt.Fprint(lcd, "     - Adafruit")
 	delay(10) // we suggest putting delays after each command
 }
 
-func loop(lcd serial_lcd.LCD) {
-	var red, green, blue byte
-	// adjust colors
-	for red = 0; red < 255; red++ {
-		lcd.SetBG(red, 0, 255-red)
-		delay(1) // give it some time to adjust the backlight!
-	}
-
-	for green = 0; green < 255; green++ {
-		lcd.SetBG(255-green, green, 0)
-		delay(1) // give it some time to adjust the backlight!
-	}
-
-	for blue = 0; blue < 255; blue++ {
-		lcd.SetBG(0, 255-blue, blue)
-		delay(1) // give it some time to adjust the backlight!
-	}
-}
-
 func delay(ms int) { time.Sleep(time.Duration(ms) * time.Millisecond) }
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/augustoroman/serial_lcd"
+)
+
+// config holds the server's settings.  They can be loaded from a JSON file with
+// the -config flag, and each one can also be set with a flag of the same name.
+type config struct {
+	Port       string `json:"port"`
+	Baud       int    `json:"baud"`
+	Addr       string `json:"addr"`
+	Cols       uint   `json:"cols"`
+	Rows       uint   `json:"rows"`
+	Brightness uint   `json:"brightness"`
+	Contrast   uint   `json:"contrast"`
+	Background string `json:"background"` // a color name or hex color
+	Splash     string `json:"splash"`
+	NoSplash   bool   `json:"no-splash"`
+}
+
+func defaultConfig() config {
+	return config{
+		Port:       "/dev/tty.usbmodem1451",
+		Baud:       9600,
+		Addr:       ":12000",
+		Cols:       16,
+		Rows:       2,
+		Brightness: 255,
+		Contrast:   200,
+		Background: "white",
+		Splash:     "Hi there!",
+	}
+}
+
+// flags defines the command-line flags for c's settings.
+func (c *config) flags() {
+	flag.StringVar(&c.Port, "port", c.Port, "COM port that LCD is on.")
+	flag.IntVar(&c.Baud, "baud", c.Baud, "Baud rate to communicate at.")
+	flag.StringVar(&c.Addr, "addr", c.Addr, "Web address to bind to.")
+	flag.UintVar(&c.Cols, "cols", c.Cols, "Number of columns of the LCD.")
+	flag.UintVar(&c.Rows, "rows", c.Rows, "Number of rows of the LCD.")
+	flag.UintVar(&c.Brightness, "brightness", c.Brightness, "Backlight brightness, 0-255.")
+	flag.UintVar(&c.Contrast, "contrast", c.Contrast, "Contrast, 0-255.")
+	flag.StringVar(&c.Background, "background", c.Background, "Background color, as a name or hex color.")
+	flag.StringVar(&c.Splash, "splash", c.Splash, "Text to show at startup.")
+	flag.BoolVar(&c.NoSplash, "no-splash", c.NoSplash, "Start with a blank display instead of the -splash text.")
+}
+
+// load replaces c with the defaults overridden by the settings in a JSON
+// file, so that a setting removed from the file goes back to its default.  The
+// command-line flags are parsed again afterwards so that they override both.
+// On error, c is unchanged.
+func (c *config) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	loaded := defaultConfig()
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("reading %s: %v", path, err)
+	}
+	*c = loaded
+	return flag.CommandLine.Parse(os.Args[1:])
+}
+
+// apply sends the size, brightness, contrast and background color to the LCD.
+// The backpack saves each of them to its EEPROM, which wears out, so if prev is
+// the config that was applied before, only the settings that changed since are
+// sent.  With a nil prev, such as at startup, all of them are.
+func (c *config) apply(lcd serial_lcd.LCD, prev *config) error {
+	bg, ok := parseColor(c.Background)
+	if !ok {
+		return fmt.Errorf("invalid background color %q", c.Background)
+	}
+	if c.Cols < 1 || c.Cols > 255 || c.Rows < 1 || c.Rows > 255 {
+		return fmt.Errorf("invalid size %dx%d", c.Cols, c.Rows)
+	}
+	all := prev == nil
+	if all {
+		prev = &config{}
+	}
+	prevBG, prevOK := parseColor(prev.Background)
+	return lcd.Batch(func(l serial_lcd.LCD) error {
+		if all || c.Cols != prev.Cols || c.Rows != prev.Rows {
+			if err := l.SetSize(uint8(c.Cols), uint8(c.Rows)); err != nil {
+				return err
+			}
+		}
+		if all || c.Contrast != prev.Contrast {
+			if err := l.SetContrast(uint8(min(c.Contrast, 255))); err != nil {
+				return err
+			}
+		}
+		if all || c.Brightness != prev.Brightness {
+			if err := l.SetBrightness(uint8(min(c.Brightness, 255))); err != nil {
+				return err
+			}
+		}
+		if all || !prevOK || bg != prevBG {
+			return l.SetBG(bg.R, bg.G, bg.B)
+		}
+		return nil
+	})
+}
+
+// watchInterval is how often watch checks the config file for changes.
+const watchInterval = time.Second
+
+// watch reloads the config file at path whenever it changes and applies the new
+// settings to the display.  The port, baud rate, address and size are only
+// used at startup, so changing them has no effect until the server is
+// restarted.
+//
+// The file is polled every watchInterval rather than watched with inotify and
+// friends, which keeps the server free of platform-specific dependencies.
+// Checking the size as well as the modification time catches edits within the
+// same second on file systems with coarse timestamps.
+func (c *config) watch(path string, d *serial_lcd.Display) error {
+	last, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for range time.Tick(watchInterval) {
+			fi, err := os.Stat(path)
+			if err != nil {
+				continue // probably being replaced by an editor
+			}
+			if fi.ModTime().Equal(last.ModTime()) && fi.Size() == last.Size() {
+				continue
+			}
+			last = fi
+			prev := *c
+			if err := c.load(path); err != nil {
+				log.Printf("Reloading config: %v", err)
+				continue
+			}
+			c.Port, c.Cols, c.Rows = prev.Port, prev.Cols, prev.Rows
+			if err := c.apply(d.LCD(), &prev); err != nil {
+				log.Printf("Applying config: %v", err)
+			}
+			d.Flush()
+		}
+	}()
+	return nil
+}
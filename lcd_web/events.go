@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/augustoroman/serial_lcd"
+)
+
+// DisplayEvents returns a handler for server-sent events that sends the
+// display's LCDState as JSON when the page connects and whenever the display
+// changes, so that the page can show a live preview of the LCD.  If changes
+// come faster than they can be sent, only the latest is sent.
+func DisplayEvents(d *serial_lcd.Display) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+
+		var mu sync.Mutex
+		latest := d.State()
+		changed := make(chan struct{}, 1)
+		changed <- struct{}{}
+		cancel := d.OnChange(func(s serial_lcd.LCDState) {
+			mu.Lock()
+			latest = s
+			mu.Unlock()
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+		defer cancel()
+
+		for {
+			select {
+			case <-changed:
+			case <-r.Context().Done():
+				return
+			}
+			mu.Lock()
+			s := latest
+			mu.Unlock()
+			data, err := json.Marshal(s)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	})
+}
@@ -2,71 +2,126 @@
 package main
 
 import (
+	"embed"
 	"flag"
-	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/augustoroman/serial_lcd"
-	"github.com/go-martini/martini"
-	"github.com/pwaller/go-hexcolor"
 )
 
 func main() {
-	port := flag.String("port", "/dev/tty.usbmodem1451", "COM port that LCD is on.")
-	baud := flag.Int("baud", 9600, "Baud rate to communicate at.")
-	addr := flag.String("addr", ":12000", "Web address to bind to.")
+	cfg := defaultConfig()
+	cfg.flags()
+	configFile := flag.String("config", "", "JSON file to load the settings from.  Flags override it, and the LCD is updated when it changes.")
 	flag.Parse()
-	lcd, err := serial_lcd.Open(*port, *baud)
+	if *configFile != "" {
+		if err := cfg.load(*configFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	lcd, err := serial_lcd.Open(cfg.Port, cfg.Baud)
 	if err != nil {
 		log.Fatal(err)
 	}
 	lcd.On()
-	lcd.SetSize(16, 2)
-	lcd.Clear()
-	lcd.Home()
-	fmt.Fprintf(lcd, "Hi there!")
-
-	s := &server{lcd}
-
-	m := martini.Classic()
-	m.Handlers(martini.Recovery())
-	m.Get("/", func() string { return home })
-	m.Post("/set", s.Set)
-	http.ListenAndServe(*addr, m)
+	if err := cfg.apply(lcd, nil); err != nil {
+		log.Fatal(err)
+	}
+	d := serial_lcd.NewDisplay(lcd)
+	if !cfg.NoSplash {
+		showText(d, cfg.Splash)
+	}
+	d.Flush()
+	addr := cfg.Addr // cfg changes if the config file is reloaded
+	if *configFile != "" {
+		if err := cfg.watch(*configFile, d); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	s := &Server{d}
+	log.Fatal(http.ListenAndServe(addr, s.Handler()))
 }
 
-type server struct{ lcd serial_lcd.LCD }
+//go:embed static
+var static embed.FS
+
+// staticFiles is the web page, with index.html served for "/".
+var staticFiles, _ = fs.Sub(static, "static")
+
+// Server serves the web page for controlling the display, along with the
+// display's JSON API and live preview.
+type Server struct{ Display *serial_lcd.Display }
 
-func (s *server) Set(w http.ResponseWriter, r *http.Request) {
+// Handler returns the handler for all of the server's pages.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServer(http.FS(staticFiles)))
+	mux.HandleFunc("POST /set", s.Set)
+	mux.Handle("GET /events", DisplayEvents(s.Display))
+	mux.Handle("/api/", s.Display)
+	return mux
+}
+
+// Set updates the display from the form values sent by the page.
+func (s *Server) Set(w http.ResponseWriter, r *http.Request) {
+	lcd := s.Display.LCD()
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	if b, ok := getByte("brightness", r.Form); ok {
-		s.lcd.SetBrightness(b)
+		lcd.SetBrightness(b)
 	}
 	if c, ok := getByte("contrast", r.Form); ok {
-		s.lcd.SetContrast(c)
+		lcd.SetContrast(c)
 	}
 	if r, g, b, ok := getRGB(r.Form); ok {
-		s.lcd.SetBG(r, g, b)
+		lcd.SetBG(r, g, b)
 	}
 	if a, ok := getText("autoscroll", r.Form); ok {
-		s.lcd.SetAutoscroll(a == "true")
+		lcd.SetAutoscroll(a == "true")
 	}
 
 	if on, ok := getText("on", r.Form); ok {
-		s.lcd.SetOn(on == "true")
+		lcd.SetOn(on == "true")
 	}
 
 	if vals, ok := r.Form["txt"]; ok && len(vals) == 1 {
-		s.lcd.Clear()
-		s.lcd.Home()
-		fmt.Fprint(s.lcd, vals[0])
+		showText(s.Display, vals[0])
 	}
+	// Flushing also updates the preview with the new settings.
+	s.Display.Flush()
+}
+
+// showText replaces the display's content with text, wrapped the way it would
+// wrap on the LCD.  The LCD isn't updated until the display is flushed.
+func showText(d *serial_lcd.Display, text string) {
+	d.Clear()
+	cols, rows := d.Size()
+	for i, line := range wrap(text, int(cols)) {
+		if i < int(rows) {
+			d.SetText(0, uint8(i), line)
+		}
+	}
+}
+
+// wrap splits text into lines at newlines and every width characters, the way
+// it would wrap on the LCD.
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		for len(line) > width {
+			lines, line = append(lines, line[:width]), line[width:]
+		}
+		lines = append(lines, line)
+	}
+	return lines
 }
 
 func getByte(key string, vals url.Values) (byte, bool) {
@@ -82,35 +137,25 @@ func getByte(key string, vals url.Values) (byte, bool) {
 
 func getRGB(vals url.Values) (r, g, b byte, ok bool) {
 	if txt, ok := getText("background", vals); ok {
-		r, g, b, _ := hexcolor.HexToRGBA(hexcolor.Hex(txt))
-		return r, g, b, true
+		if c, ok := parseColor(txt); ok {
+			return c.R, c.G, c.B, true
+		}
 	}
 	return 0, 0, 0, false
 }
 
+// parseColor parses a color name from the default palette or a hex color.
+func parseColor(txt string) (serial_lcd.Color, bool) {
+	if c, ok := serial_lcd.DefaultPalette[strings.ToLower(txt)]; ok {
+		return c, true
+	}
+	c, err := serial_lcd.ParseColor(txt)
+	return c, err == nil
+}
+
 func getText(key string, vals url.Values) (string, bool) {
 	if val, ok := vals[key]; ok && len(val) == 1 {
 		return val[0], true
 	}
 	return "", false
 }
-
-var home = `<html>
-<body>
-LCD Control:
-<hr>
-Text:<br><textarea rows=2 cols=16 oninput="set({txt:this.value})" onchange="set({txt:this.value})">Hi there!</textarea><br>
-Brightness: <input min=0 max=255 step=1 type=range
-  oninput="set({brightness:this.value})" onchange="set({brightness:this.value})"><br>
-Contrast: <input min=0 max=255 step=1 type=range
-  oninput="set({contrast:this.value})" onchange="set({contrast:this.value})"><br>
-Background: <input type=color oninput="set({background:this.value})" onchange="set({background:this.value})"><br>
-Autoscroll: <input type=checkbox onchange="set({autoscroll:this.checked})"><br>
-On: <input type=checkbox onchange="set({on:this.checked})"><br>
-<script src="//ajax.googleapis.com/ajax/libs/jquery/2.1.1/jquery.min.js"></script>
-<script>
-function set(vals) { $.post("/set", vals); }
-</script>
-</body>
-</html>
-`
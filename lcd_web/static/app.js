@@ -0,0 +1,23 @@
+function set(vals) { fetch("/set", {method: "POST", body: new URLSearchParams(vals)}); }
+
+// Draw the display's state as a low resolution image with 5x8 pixel
+// characters, scaled up so that the pixels show.
+function draw(s) {
+  var c = document.getElementById("lcd"), ctx = c.getContext("2d");
+  c.width = s.cols * 6;
+  c.height = s.rows * 9;
+  c.style.width = (c.width * 5) + "px";
+  var k = s.on ? s.brightness / 255 : 0.1;
+  ctx.fillStyle = "rgb(" + [s.bg.R, s.bg.G, s.bg.B].map(function(v) { return Math.round(v * k); }) + ")";
+  ctx.fillRect(0, 0, c.width, c.height);
+  ctx.fillStyle = "#000";
+  ctx.font = "8px monospace";
+  ctx.textBaseline = "top";
+  s.lines.forEach(function(line, row) {
+    for (var col = 0; col < line.length; col++) {
+      ctx.fillText(line[col], col * 6, row * 9, 5);
+    }
+  });
+}
+var events = new EventSource("/events");
+events.onmessage = function(e) { draw(JSON.parse(e.data)); };
@@ -0,0 +1,47 @@
+package serial_lcd
+
+import (
+	"errors"
+	"sync"
+)
+
+// MultiDisplay is a group of displays that are updated together, such as the
+// customer-facing and operator-facing displays of a point-of-sale terminal.
+type MultiDisplay struct {
+	displays []*Display
+}
+
+// NewMultiDisplay returns a MultiDisplay for the given displays.  The first one
+// is the primary display.
+func NewMultiDisplay(displays ...*Display) *MultiDisplay {
+	return &MultiDisplay{append([]*Display(nil), displays...)}
+}
+
+// All returns the displays in the group.
+func (m *MultiDisplay) All() []*Display { return append([]*Display(nil), m.displays...) }
+
+// Primary returns the first display in the group, or nil if the group is empty.
+// Use it for anything that should only be done once for the whole group.
+func (m *MultiDisplay) Primary() *Display {
+	if len(m.displays) == 0 {
+		return nil
+	}
+	return m.displays[0]
+}
+
+// Broadcast calls fn for every display in the group concurrently, so that a
+// slow display doesn't hold up the others.  It waits for all of them to finish
+// and returns their errors joined together.
+func (m *MultiDisplay) Broadcast(fn func(*Display) error) error {
+	errs := make([]error, len(m.displays))
+	var wg sync.WaitGroup
+	for i, d := range m.displays {
+		wg.Add(1)
+		go func(i int, d *Display) {
+			defer wg.Done()
+			errs[i] = fn(d)
+		}(i, d)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,176 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestOpen_SendsClearOnInit(t *testing.T) {
+	driver, port := newPipePair(t)
+	fakeOpen(t, port)
+	w := listen(t, driver)
+
+	lcd, err := Open("/dev/ttyUSB0", 9600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.expectNothing() // the clear waits for the first write
+	fmt.Fprint(lcd, "hi")
+	w.expect(cat(cmd(CLEAR), []byte("hi"))...)
+	fmt.Fprint(lcd, "!")
+	w.expect('!')
+}
+
+func TestOpen_WithClearOnOpenFalse(t *testing.T) {
+	driver, port := newPipePair(t)
+	fakeOpen(t, port)
+	w := listen(t, driver)
+
+	lcd, err := Open("/dev/ttyUSB0", 9600, WithClearOnOpen(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(lcd, "hi")
+	w.expect('h', 'i')
+}
+
+func TestOpen_Retries(t *testing.T) {
+	_, port := newPipePair(t)
+	orig := openPort
+	defer func() { openPort = orig }()
+	fails := 2
+	openPort = func(string, int) (io.ReadWriteCloser, error) {
+		if fails > 0 {
+			fails--
+			return nil, errors.New("no such device")
+		}
+		return port, nil
+	}
+	if _, err := Open("/dev/ttyUSB0", 9600, WithRetry(3, time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	fails = 5
+	_, err := Open("/dev/ttyUSB0", 9600, WithRetry(2, time.Millisecond))
+	if err == nil {
+		t.Fatal("Open succeeded without a port")
+	}
+}
+
+func TestOpen_RejectsBadPortNames(t *testing.T) {
+	fakeOpen(t, &memPort{})
+	if _, err := Open("ttyUSB0", 9600); err == nil {
+		t.Error("Open accepted a port name without a path")
+	}
+}
+
+func TestCommandsSent(t *testing.T) {
+	driver, port := newPipePair(t)
+	w := listen(t, driver)
+	lcd := New(port)
+
+	tests := []struct {
+		name string
+		do   func() error
+		want []byte
+	}{
+		{"Clear", lcd.Clear, cmd(CLEAR)},
+		{"Home", lcd.Home, cmd(GO_HOME)},
+		{"MoveTo", func() error { return lcd.MoveTo(3, 2) }, cmd(SET_CURSOR_POSITION, 3, 2)},
+		{"SetSize", func() error { return lcd.SetSize(20, 4) }, cmd(SET_LCD_SIZE, 20, 4)},
+		{"SetBrightness", func() error { return lcd.SetBrightness(128) }, cmd(BRIGHTNESS, 128)},
+		{"SetContrast", func() error { return lcd.SetContrast(180) }, cmd(CONTRAST, 180)},
+		{"SetBG", func() error { return lcd.SetBG(1, 2, 3) }, cmd(SET_RGB_BACKLIGHT_COLOR, 1, 2, 3)},
+		{"Off", lcd.Off, cmd(BACKLIGHT_OFF)},
+		{"SetAutoscroll", func() error { return lcd.SetAutoscroll(true) }, cmd(AUTOSCROLL_ON)},
+		{"CreateCustomChar", func() error { return lcd.CreateCustomChar(2, Char{1, 2, 3, 4, 5, 6, 7, 8}) },
+			cmd(CREATE_CUSTOM_CHARACTER, 2, 1, 2, 3, 4, 5, 6, 7, 8)},
+		{"Write", func() error { _, err := fmt.Fprint(lcd, "abc"); return err }, []byte("abc")},
+	}
+	for _, test := range tests {
+		if err := test.do(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		w.expect(test.want...)
+	}
+	w.expectNothing()
+}
+
+func TestClosedLCD(t *testing.T) {
+	lcd, _ := newMemLCD()
+	lcd.Close()
+	if err := lcd.Clear(); err != ErrClosed {
+		t.Errorf("Clear after Close returned %v, want ErrClosed", err)
+	}
+}
+
+func TestBacklightOnQuirk(t *testing.T) {
+	lcd, port := newMemLCD()
+	if err := lcd.On(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := port.Bytes(), cmd(BACKLIGHT_ON, 0); !bytes.Equal(got, want) {
+		t.Errorf("On sent %q, want %q", got, want)
+	}
+
+	port.Reset()
+	lcd.SetFirmwareQuirks(QuirkNoBacklightOnArg)
+	if err := lcd.On(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := port.Bytes(), cmd(BACKLIGHT_ON); !bytes.Equal(got, want) {
+		t.Errorf("On with QuirkNoBacklightOnArg sent %q, want %q", got, want)
+	}
+
+	// Off is the same either way.
+	for _, q := range []FirmwareQuirks{0, QuirkNoBacklightOnArg} {
+		port.Reset()
+		lcd.SetFirmwareQuirks(q)
+		if err := lcd.Off(); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := port.Bytes(), cmd(BACKLIGHT_OFF); !bytes.Equal(got, want) {
+			t.Errorf("Off with quirks %v sent %q, want %q", q, got, want)
+		}
+	}
+}
+
+func TestOpen_WithSelfTest(t *testing.T) {
+	shortReadTimeout(t)
+
+	// The stock firmware never replies, which isn't a failure.
+	driver, port := newPipePair(t)
+	fakeOpen(t, port)
+	w := listen(t, driver)
+	if _, err := Open("/dev/ttyUSB0", 9600, WithSelfTest(true)); err != nil {
+		t.Errorf("self-test without a reply failed: %v", err)
+	}
+	w.expect(cmd(READ_VERSION)...)
+
+	// A port that can't be written to is.
+	fakeOpen(t, &failingPort{err: errors.New("input/output error")})
+	if _, err := Open("/dev/ttyUSB0", 9600, WithSelfTest(true)); err == nil {
+		t.Error("self-test on a broken port succeeded")
+	}
+}
+
+func TestLCDFromConnection(t *testing.T) {
+	p := &memPort{}
+	lcd := LCD{ReadWriteCloser: p}
+	if err := lcd.MoveTo(3, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := lcd.Batch(func(l LCD) error { return l.Raw('x') }); err != nil {
+		t.Fatal(err)
+	}
+	if col, row := lcd.CursorPos(); col != 4 || row != 2 {
+		t.Errorf("cursor is at %d,%d, want 4,2", col, row)
+	}
+	if got, want := p.Bytes(), cat(cmd(SET_CURSOR_POSITION, 3, 2), []byte("x")); !bytes.Equal(got, want) {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+package serial_lcd
+
+import (
+	"log"
+	"time"
+)
+
+// Option configures how Open connects to the LCD.
+type Option func(*options)
+
+type options struct {
+	attempts    int           // number of times to try opening the port, <0 is forever
+	backoff     time.Duration // delay between open attempts
+	logger      *log.Logger   // optional, nil disables logging
+	clearOnOpen bool          // clear the display with the first write
+	selfTest    bool          // check that the backpack replies after opening
+}
+
+func defaultOptions() options { return options{attempts: 1, clearOnOpen: true} }
+
+// WithRetry makes Open try to open the port up to attempts times, waiting
+// backoff between each try.  This is useful when the backpack is plugged in but
+// the OS hasn't created the device node yet.  An attempts value of -1 retries
+// forever.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) { o.attempts, o.backoff = attempts, backoff }
+}
+
+// WithLogger sets a logger used to report problems that aren't returned as
+// errors, such as failed open attempts that were retried.
+func WithLogger(l *log.Logger) Option { return func(o *options) { o.logger = l } }
+
+func (o options) logf(format string, args ...interface{}) {
+	if o.logger != nil {
+		o.logger.Printf(format, args...)
+	}
+}
+
+// WithClearOnOpen sets whether the display is cleared after opening it, which
+// is on by default.  Rather than clearing right away, the clear is sent in the
+// same write as the first thing sent to the LCD, so whatever was on the display
+// before is replaced without a visible blank screen in between.  Building the
+// first screen inside LCD.Batch sends all of it along with the clear.
+func WithClearOnOpen(clear bool) Option { return func(o *options) { o.clearOnOpen = clear } }
+
+// WithSelfTest sets whether Open checks the link to the backpack, which is off
+// by default.  The check asks for the firmware version and fails if the query
+// can't be sent or the reply can't be read, such as when the port is gone or
+// misconfigured.  The stock Adafruit firmware never replies, so no reply
+// within ReadTimeout isn't an error, only logged with WithLogger; that also
+// means the check can't tell that nothing is connected to the port at all.
+func WithSelfTest(test bool) Option { return func(o *options) { o.selfTest = test } }
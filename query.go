@@ -0,0 +1,223 @@
+package serial_lcd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrNotSupported is returned for operations that the backpack's firmware
+// doesn't implement.
+var ErrNotSupported = errors.New("serial_lcd: not supported by the firmware")
+
+// ReadTimeout is how long to wait for the backpack to reply to a query.
+var ReadTimeout = 500 * time.Millisecond
+
+// errReadTimeout is returned by read when no reply arrives in time.
+var errReadTimeout = errors.New("serial_lcd: timed out waiting for a reply")
+
+// replyBuffer is how many received bytes are kept for read.  More than that
+// are dropped until they're read.
+const replyBuffer = 64
+
+// replies returns the bytes received from the backpack.  The serial port can't
+// cancel a read, so a single reader is started the first time this is called
+// and keeps reading until the connection fails or is closed, at which point
+// the channel is closed and the error is left in readErr.
+func (l LCD) replies() <-chan byte {
+	l.state().readerOnce.Do(func() {
+		ch := make(chan byte, replyBuffer)
+		l.state().replyCh = ch
+		go func(r io.Reader) {
+			buf := make([]byte, replyBuffer)
+			for {
+				n, err := r.Read(buf)
+				for _, b := range buf[:n] {
+					select {
+					case ch <- b:
+					default:
+					}
+				}
+				if err != nil {
+					l.state().mu.Lock()
+					l.state().readErr = err
+					l.state().mu.Unlock()
+					close(ch)
+					return
+				}
+			}
+		}(l.ReadWriteCloser)
+	})
+	return l.state().replyCh
+}
+
+// query sends a command and reads an n byte reply, giving up after
+// ReadTimeout.  Bytes left over from earlier replies, such as ones that
+// arrived too late, are discarded first, and only one query is sent at a
+// time so that replies go to the right caller.
+func (l LCD) query(n int, cmd byte, args ...byte) ([]byte, error) {
+	l.state().query.Lock()
+	defer l.state().query.Unlock()
+	ch := l.replies()
+	for stale := true; stale; {
+		select {
+		case _, ok := <-ch:
+			stale = ok
+		default:
+			stale = false
+		}
+	}
+	if err := l.Command(cmd, args...); err != nil {
+		return nil, err
+	}
+	return l.read(ch, n)
+}
+
+// read reads n bytes from ch, giving up after ReadTimeout.
+func (l LCD) read(ch <-chan byte, n int) ([]byte, error) {
+	data := make([]byte, 0, n)
+	timeout := time.NewTimer(ReadTimeout)
+	defer timeout.Stop()
+	for len(data) < n {
+		select {
+		case b, ok := <-ch:
+			if !ok {
+				l.state().mu.Lock()
+				defer l.state().mu.Unlock()
+				if l.state().readErr == io.EOF {
+					return nil, errReadTimeout // no replies will ever come
+				}
+				return nil, l.state().readErr
+			}
+			data = append(data, b)
+		case <-timeout.C:
+			return nil, errReadTimeout
+		}
+	}
+	return data, nil
+}
+
+// Version asks the backpack for its firmware version, using the Matrix Orbital
+// compatible READ_VERSION command.  It returns ErrNotSupported if there's no
+// reply within ReadTimeout, which is the case for the stock Adafruit firmware,
+// or if the connection can't reply at all, as for NullLCD.
+func (l LCD) Version() (string, error) {
+	reply, err := l.query(1, READ_VERSION)
+	if err == errReadTimeout {
+		return "", ErrNotSupported
+	} else if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d", reply[0]>>4, reply[0]&0x0F), nil
+}
+
+// selfTest checks that a version query can be sent and its reply read.  No
+// reply at all proves nothing, since the stock firmware never answers, so that
+// only gets logged.
+func (l LCD) selfTest() error {
+	v, err := l.Version()
+	switch err {
+	case nil:
+		l.logf("serial_lcd: firmware version %s", v)
+	case ErrNotSupported:
+		l.logf("serial_lcd: no reply to the version query, the firmware is unknown")
+	default:
+		return err
+	}
+	return nil
+}
+
+// FirmwareInfo describes the backpack's firmware.
+type FirmwareInfo struct {
+	Version string
+	// Capabilities lists the optional commands that the firmware was found to
+	// answer, such as "READ_VERSION".  The version reply is a single byte that
+	// doesn't describe any features itself, so this is only what the queries
+	// showed.  It's nil when the firmware is unknown.
+	Capabilities []string
+}
+
+// FirmwareInfo queries the backpack's firmware, see Version.
+func (l LCD) FirmwareInfo() (FirmwareInfo, error) {
+	v, err := l.Version()
+	if err != nil {
+		return FirmwareInfo{}, err
+	}
+	return FirmwareInfo{Version: v, Capabilities: []string{commands[READ_VERSION].name}}, nil
+}
+
+// Ping checks that the LCD can still be written to without visibly changing
+// it, by moving the cursor to where it already is.  The backpack doesn't
+// acknowledge commands, so this detects a disconnected or closed port rather
+// than a display that has stopped responding.
+func (l LCD) Ping() error {
+	col, row := l.CursorPos()
+	return l.exclusive(func(l LCD) error {
+		if n, ok := l.ReadWriteCloser.(nopOnError); ok {
+			l.ReadWriteCloser = n.ReadWriteCloser // report the errors NopOnError hides
+		}
+		return l.MoveTo(col, row)
+	})
+}
+
+// IsConnected reports whether Ping succeeds.
+func (l LCD) IsConnected() bool { return l.Ping() == nil }
+
+// IsBusy reports whether the HD44780 controller is busy processing a command,
+// as read from its busy flag.  The backpack doesn't connect the controller's
+// R/W pin, so current firmware always returns ErrNotSupported.
+func (l LCD) IsBusy() (bool, error) { return false, ErrNotSupported }
+
+// ReadCursorAddress reads the HD44780's address counter, which gives the true
+// cursor position.  Like IsBusy, this needs firmware support that doesn't
+// exist yet, so it always returns ErrNotSupported; see CursorPos for the
+// tracked position instead.
+func (l LCD) ReadCursorAddress() (uint8, error) { return 0, ErrNotSupported }
+
+// readyPoll is how often WaitReady asks the backpack whether it's ready.
+const readyPoll = 100 * time.Millisecond
+
+// WaitReady waits for the backpack to finish starting up, for up to timeout.
+// Opening the port resets the backpack on most boards, and anything sent while
+// it starts up is lost, which often swallows the first Clear or SetSize.  Call
+// WaitReady right after Open, before sending anything else.
+//
+// It returns as soon as the backpack replies to a version query.  Firmware
+// that doesn't answer them, such as the stock Adafruit firmware, can't be
+// polled, so then WaitReady waits for the whole timeout and returns nil,
+// acting as a fixed start-up delay.  Around two seconds is usually enough.
+// A connection that can never reply, such as NullLCD, is a plain sleep too.
+func (l LCD) WaitReady(timeout time.Duration) error {
+	l.state().query.Lock()
+	defer l.state().query.Unlock()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ch := l.replies()
+	ticker := time.NewTicker(readyPoll)
+	defer ticker.Stop()
+	for {
+		// Write the query directly, so that it isn't delayed by SetMinInterval
+		// and doesn't take along a pending clear that might be lost.
+		if _, err := l.ReadWriteCloser.Write([]byte{COMMAND, READ_VERSION}); err != nil {
+			return err
+		}
+		select {
+		case _, ok := <-ch:
+			if ok {
+				return nil
+			}
+			l.state().mu.Lock()
+			err := l.state().readErr
+			l.state().mu.Unlock()
+			if err != io.EOF {
+				return err
+			}
+			<-deadline.C // nothing can reply, so just wait
+			return nil
+		case <-deadline.C:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
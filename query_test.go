@@ -0,0 +1,131 @@
+package serial_lcd
+
+import (
+	"testing"
+	"time"
+)
+
+// shortReadTimeout makes queries give up quickly until the test ends.
+func shortReadTimeout(t *testing.T) {
+	orig := ReadTimeout
+	ReadTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { ReadTimeout = orig })
+}
+
+func TestVersion(t *testing.T) {
+	driver, port := newPipePair(t)
+	w := listen(t, driver)
+	lcd := New(port)
+
+	go func() {
+		w.expect(cmd(READ_VERSION)...)
+		driver.Write([]byte{0x12})
+	}()
+	v, err := lcd.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "1.2" {
+		t.Errorf("Version = %q, want 1.2", v)
+	}
+}
+
+func TestVersion_LateReplyIsDiscarded(t *testing.T) {
+	shortReadTimeout(t)
+	driver, port := newPipePair(t)
+	w := listen(t, driver)
+	lcd := New(port)
+
+	if _, err := lcd.Version(); err != ErrNotSupported {
+		t.Fatalf("Version without a reply returned %v, want ErrNotSupported", err)
+	}
+	w.expect(cmd(READ_VERSION)...)
+	driver.Write([]byte{0x11}) // too late for the first query
+	time.Sleep(10 * time.Millisecond)
+
+	go func() {
+		w.expect(cmd(READ_VERSION)...)
+		driver.Write([]byte{0x23})
+	}()
+	v, err := lcd.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "2.3" {
+		t.Errorf("Version = %q after a late reply, want 2.3", v)
+	}
+}
+
+func TestVersion_NullLCD(t *testing.T) {
+	start := time.Now()
+	if _, err := NullLCD().Version(); err != ErrNotSupported {
+		t.Errorf("NullLCD().Version() returned %v, want ErrNotSupported", err)
+	}
+	if d := time.Since(start); d >= ReadTimeout {
+		t.Errorf("NullLCD().Version() took %v, want it to return without waiting", d)
+	}
+}
+
+func TestVersion_Closed(t *testing.T) {
+	lcd, _ := newMemLCD()
+	lcd.Close()
+	if _, err := lcd.Version(); err != ErrClosed {
+		t.Errorf("Version after Close returned %v, want ErrClosed", err)
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	driver, port := newPipePair(t)
+	w := listen(t, driver)
+	lcd := New(port)
+
+	go func() {
+		w.expect(cmd(READ_VERSION)...)
+		w.expect(cmd(READ_VERSION)...) // lost while starting up
+		driver.Write([]byte{0x12})
+	}()
+	start := time.Now()
+	if err := lcd.WaitReady(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d >= time.Second {
+		t.Errorf("WaitReady took %v after a reply, want it to return early", d)
+	}
+}
+
+func TestWaitReady_NoReply(t *testing.T) {
+	driver, port := newPipePair(t)
+	listen(t, driver)
+	for _, lcd := range []LCD{New(port), NullLCD()} {
+		start := time.Now()
+		if err := lcd.WaitReady(250 * time.Millisecond); err != nil {
+			t.Errorf("WaitReady without a reply returned %v", err)
+		}
+		if d := time.Since(start); d < 250*time.Millisecond {
+			t.Errorf("WaitReady without a reply returned after %v, want the whole timeout", d)
+		}
+	}
+}
+
+func TestFirmwareInfo(t *testing.T) {
+	driver, port := newPipePair(t)
+	w := listen(t, driver)
+	lcd := New(port)
+
+	go func() {
+		w.expect(cmd(READ_VERSION)...)
+		driver.Write([]byte{0x21})
+	}()
+	info, err := lcd.FirmwareInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != "2.1" || len(info.Capabilities) != 1 || info.Capabilities[0] != "READ_VERSION" {
+		t.Errorf("FirmwareInfo = %+v, want version 2.1 answering READ_VERSION", info)
+	}
+
+	info, err = NullLCD().FirmwareInfo()
+	if err != ErrNotSupported || info.Capabilities != nil {
+		t.Errorf("NullLCD().FirmwareInfo() = %+v, %v, want no capabilities and ErrNotSupported", info, err)
+	}
+}
@@ -0,0 +1,264 @@
+package serial_lcd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// NumCustomChars is the number of custom character slots on the LCD.
+const NumCustomChars = 8
+
+// ErrNoFreeSlots is returned when registering a custom character while all of
+// the slots are taken.
+var ErrNoFreeSlots = errors.New("serial_lcd: all custom character slots are in use")
+
+// CharRegistry keeps track of which named custom character is in which slot,
+// so that parts of a program don't need to agree on slot numbers up front.
+// Register characters, Flush them to the LCD, then look up each one's slot to
+// display it.  The zero value is an empty registry.
+type CharRegistry struct {
+	slots     [NumCustomChars]registered
+	avoidZero bool
+}
+
+type registered struct {
+	name string
+	char Char
+	used bool
+}
+
+// Register adds a named character to the first free slot and returns the slot.
+// Registering a name again replaces its character and keeps its slot.
+func (r *CharRegistry) Register(name string, c Char) (slot uint8, err error) {
+	if i, ok := r.find(name); ok {
+		r.slots[i].char = c
+		return uint8(i), nil
+	}
+	for n := range r.slots {
+		i := n
+		if r.avoidZero {
+			i = (n + 1) % NumCustomChars
+		}
+		if !r.slots[i].used {
+			r.slots[i] = registered{name, c, true}
+			return uint8(i), nil
+		}
+	}
+	return 0, ErrNoFreeSlots
+}
+
+// AvoidSlotZero sets whether Register leaves slot 0 until the other slots are
+// full.  Slot 0 is shown by writing a NUL byte, which is easily lost in code
+// that treats text as C strings, such as some loggers and serial bridges.
+func (r *CharRegistry) AvoidSlotZero(avoid bool) { r.avoidZero = avoid }
+
+// Reserve pins a named character to a specific slot, moving it there if the
+// name was registered elsewhere.  It fails if another character is already in
+// the slot.
+func (r *CharRegistry) Reserve(name string, slot uint8, c Char) error {
+	if err := checkSlot(slot); err != nil {
+		return err
+	}
+	if cur := r.slots[slot]; cur.used && cur.name != name {
+		return fmt.Errorf("serial_lcd: custom character slot %d is already used by %q", slot, cur.name)
+	}
+	r.Unregister(name)
+	r.slots[slot] = registered{name, c, true}
+	return nil
+}
+
+// Unregister frees the slot used by a named character, if any.
+func (r *CharRegistry) Unregister(name string) {
+	if i, ok := r.find(name); ok {
+		r.slots[i] = registered{}
+	}
+}
+
+// Slot returns the slot of a named character.
+func (r *CharRegistry) Slot(name string) (uint8, error) {
+	if i, ok := r.find(name); ok {
+		return uint8(i), nil
+	}
+	return 0, fmt.Errorf("serial_lcd: no custom character named %q", name)
+}
+
+// Flush sends every registered character to its slot on the LCD.
+func (r *CharRegistry) Flush(lcd LCD) error {
+	for i, s := range r.slots {
+		if s.used {
+			if err := lcd.CreateCustomChar(uint8(i), s.char); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RegisterChar adds a named custom character to the first free slot, using a
+// CharRegistry kept with the LCD, and sends it to the LCD.  It returns the slot
+// to display the character with, e.g. with PrintChar.  Registering a name
+// again replaces its character and keeps its slot.  Helpers that need fixed
+// slots, such as SetInverted, reserve them in the same registry, so they're
+// never handed out while in use, and those helpers fail if the slot is already
+// taken by a registered character.
+func (l LCD) RegisterChar(name string, c Char) (slot uint8, err error) {
+	l.state().mu.Lock()
+	_, existed := l.state().chars.find(name)
+	slot, err = l.state().chars.Register(name, c)
+	l.state().mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if err := l.CreateCustomChar(slot, c); err != nil {
+		if !existed {
+			l.state().mu.Lock()
+			l.state().chars.Unregister(name)
+			l.state().mu.Unlock()
+		}
+		return 0, err
+	}
+	return slot, nil
+}
+
+// reserveChar pins a named character to a fixed slot in the registry kept with
+// the LCD, see CharRegistry.Reserve, and sends it to the LCD.  It's for helpers
+// that need a particular slot.
+func (l LCD) reserveChar(name string, slot uint8, c Char) error {
+	l.state().mu.Lock()
+	_, existed := l.state().chars.find(name)
+	err := l.state().chars.Reserve(name, slot, c)
+	l.state().mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := l.CreateCustomChar(slot, c); err != nil {
+		if !existed {
+			l.ReleaseChar(name)
+		}
+		return err
+	}
+	return nil
+}
+
+// RegisterIcons registers the named built-in icons from Icons with
+// RegisterChar, all in one batch.  Use CharSlot to find their slots.
+func (l LCD) RegisterIcons(names ...string) error {
+	for _, name := range names {
+		if _, ok := Icons[name]; !ok {
+			return fmt.Errorf("serial_lcd: no icon named %q", name)
+		}
+	}
+	return l.Batch(func(l LCD) error {
+		for _, name := range names {
+			if _, err := l.RegisterChar(name, Icons[name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CharSlot returns the slot of a character added with RegisterChar.
+func (l LCD) CharSlot(name string) (uint8, error) {
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	return l.state().chars.Slot(name)
+}
+
+// FreeCharSlots returns how many custom character slots RegisterChar has left.
+func (l LCD) FreeCharSlots() int {
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	return l.state().chars.Free()
+}
+
+// ReleaseChar frees the slot of a character added with RegisterChar, so that
+// it can be reused for another character.  The LCD keeps showing the old
+// character wherever it's on the screen until the slot is reused.
+func (l LCD) ReleaseChar(name string) {
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	l.state().chars.Unregister(name)
+}
+
+// PrintTemplate writes s at the cursor, replacing each {name} in it with the
+// custom character registered under that name with RegisterChar, as in
+// "Batt {battery} 80%".  Write {{ for a literal {.  An unknown name is an error
+// unless SetTemplateStrict has been turned off, in which case it's written
+// as is.
+func (l LCD) PrintTemplate(s string) error {
+	out, err := l.expandTemplate(s)
+	if err != nil {
+		return err
+	}
+	return dropN(l.Write(out))
+}
+
+func (l LCD) expandTemplate(s string) ([]byte, error) {
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	var out []byte
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			out, s = append(out, s...), ""
+			break
+		}
+		out, s = append(out, s[:i]...), s[i:]
+		if strings.HasPrefix(s, "{{") {
+			out, s = append(out, '{'), s[2:]
+			continue
+		}
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			out, s = append(out, s...), ""
+			break
+		}
+		if slot, ok := l.state().chars.find(s[1:end]); ok {
+			out = append(out, byte(slot))
+		} else if !l.state().looseTemplates {
+			return nil, fmt.Errorf("serial_lcd: no custom character named %q", s[1:end])
+		} else {
+			out = append(out, s[:end+1]...)
+		}
+		s = s[end+1:]
+	}
+	return out, nil
+}
+
+// SetAvoidCharSlotZero sets whether RegisterChar leaves slot 0 until the other
+// slots are full, see CharRegistry.AvoidSlotZero.
+func (l LCD) SetAvoidCharSlotZero(avoid bool) {
+	l.state().mu.Lock()
+	l.state().chars.AvoidSlotZero(avoid)
+	l.state().mu.Unlock()
+}
+
+// SetTemplateStrict sets whether PrintTemplate fails on names that haven't
+// been registered, which is the default, or writes them as is.
+func (l LCD) SetTemplateStrict(strict bool) {
+	l.state().mu.Lock()
+	l.state().looseTemplates = !strict
+	l.state().mu.Unlock()
+}
+
+// Free returns the number of free slots.
+func (r *CharRegistry) Free() int {
+	n := 0
+	for _, s := range r.slots {
+		if !s.used {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *CharRegistry) find(name string) (int, bool) {
+	for i, s := range r.slots {
+		if s.used && s.name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,124 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Router splits a virtual display across several physical ones, choosing where
+// each row goes by its content.  For example, rows starting with "ALERT" can go
+// to a display at the front of a kiosk and the rest to one at the back.
+type Router struct {
+	routes []route
+	def    *Display
+	d      *Display
+	sentTo []*Display // where each row was last drawn, guarded by d's lock
+}
+
+type route struct {
+	match   func(row int, text string) bool
+	display *Display
+}
+
+// NewRouter returns a Router with no routes.
+func NewRouter() *Router { return &Router{} }
+
+// Handle sends rows for which predicate returns true to display.  Predicates
+// are checked in the order they were added and the first match wins.  The
+// predicate is given the row of the virtual display, counting from 0, and its
+// text without trailing spaces.
+func (r *Router) Handle(predicate func(row int, text string) bool, display *Display) {
+	r.routes = append(r.routes, route{predicate, display})
+}
+
+// SetDefault sets the display that rows go to when no predicate matches.
+// Without a default, those rows aren't shown.
+func (r *Router) SetDefault(display *Display) { r.def = display }
+
+// ServeDisplay returns the virtual display whose rows are routed.  It's as wide
+// as the widest display and as tall as the tallest, and it's created on the
+// first call, so add the routes before calling it.  Row n goes to row n of the
+// display it's routed to; if that display has too few rows, the row isn't
+// shown and Flush returns an error.  When a row is routed to a different
+// display than before, it's cleared from the old one.  Only rows that changed
+// are sent when the virtual display is flushed.  Its LCD discards everything
+// written to it.
+func (r *Router) ServeDisplay() *Display {
+	if r.d != nil {
+		return r.d
+	}
+	var cols, rows int
+	seen := map[*Display]bool{}
+	for _, d := range r.displays() {
+		if !seen[d] {
+			seen[d] = true
+			c, n := d.Size()
+			cols, rows = max(cols, int(c)), max(rows, int(n))
+		}
+	}
+	r.d = newDisplay(NullLCD(), uint8(cols), uint8(rows))
+	r.d.router = r
+	// The displays start out blank, so only rows that are drawn need sending.
+	r.d.shown = NewFrame(r.d.cols, r.d.rows)
+	r.sentTo = make([]*Display, r.d.rows)
+	return r.d
+}
+
+func (r *Router) displays() []*Display {
+	var all []*Display
+	for _, rt := range r.routes {
+		all = append(all, rt.display)
+	}
+	if r.def != nil {
+		all = append(all, r.def)
+	}
+	return all
+}
+
+// flush sends the rows of want that changed to the displays they're routed
+// to, and flushes those displays.
+func (r *Router) flush(d *Display, want [][]byte) error {
+	var targets []*Display
+	var errs []error
+	for n := range want {
+		if bytes.Equal(want[n], d.shown.Cells[n]) {
+			continue
+		}
+		text := string(want[n])
+		t := r.def
+		for _, rt := range r.routes {
+			if rt.match(n, strings.TrimRight(text, " ")) {
+				t = rt.display
+				break
+			}
+		}
+		copy(d.shown.Cells[n], want[n])
+		d.changed = true
+		if old := r.sentTo[n]; old != nil && old != t {
+			cols, _ := old.Size()
+			old.SetText(0, uint8(n), strings.Repeat(" ", int(cols)))
+			targets = append(targets, old)
+		}
+		r.sentTo[n] = nil
+		if t == nil {
+			continue
+		}
+		if _, rows := t.Size(); n >= int(rows) {
+			errs = append(errs, fmt.Errorf("serial_lcd: row %d is routed to a display with %d rows", n, rows))
+			continue
+		}
+		t.SetText(0, uint8(n), text)
+		r.sentTo[n] = t
+		targets = append(targets, t)
+	}
+	seen := map[*Display]bool{}
+	for _, t := range targets {
+		if !seen[t] {
+			seen[t] = true
+			errs = append(errs, t.Flush())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package serial_lcd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRouter_ClearsRerouted(t *testing.T) {
+	front, back := NewDisplay(NullLCD()), NewDisplay(NullLCD())
+	r := NewRouter()
+	r.Handle(func(row int, text string) bool { return strings.HasPrefix(text, "ALERT") }, front)
+	r.SetDefault(back)
+	d := r.ServeDisplay()
+	if cols, rows := d.Size(); cols != 16 || rows != 2 {
+		t.Fatalf("virtual display is %dx%d, want 16x2", cols, rows)
+	}
+
+	d.SetText(0, 1, "ALERT fire")
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := front.State().Lines[1]; got != "ALERT fire      " {
+		t.Errorf("front row 1 is %q after an alert", got)
+	}
+
+	d.SetText(0, 1, "all clear ")
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := front.State().Lines[1]; got != strings.Repeat(" ", 16) {
+		t.Errorf("front row 1 is %q after the row moved to the back, want it cleared", got)
+	}
+	if got := back.State().Lines[1]; got != "all clear       " {
+		t.Errorf("back row 1 is %q", got)
+	}
+}
+
+func TestRouter_RejectsOutOfRangeRows(t *testing.T) {
+	lcd := NullLCD()
+	lcd.state().rows = 1
+	small, big := NewDisplay(lcd), NewDisplay(NullLCD())
+	r := NewRouter()
+	r.Handle(func(row int, text string) bool { return row == 1 }, small)
+	r.SetDefault(big)
+	d := r.ServeDisplay()
+
+	d.SetText(0, 1, "too low")
+	if err := d.Flush(); err == nil {
+		t.Error("Flush of a row past the end of its display succeeded")
+	}
+	if got := small.State().Lines[0]; got != strings.Repeat(" ", 16) {
+		t.Errorf("row 1 was drawn on row 0 of a 1 row display: %q", got)
+	}
+}
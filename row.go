@@ -0,0 +1,51 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Row is a single row of a Display.  Its methods update the display's buffer
+// and then flush it to the LCD.
+type Row struct {
+	d *Display
+	n uint8
+}
+
+// Row returns row n of the display, counting from 0.
+func (d *Display) Row(n uint8) (Row, error) {
+	if n >= d.rows {
+		return Row{}, fmt.Errorf("serial_lcd: row %d is out of range, the display has %d rows", n, d.rows)
+	}
+	return Row{d, n}, nil
+}
+
+// Write replaces the whole row with text, blanking the rest of the row.
+func (r Row) Write(text string) error {
+	r.d.mu.Lock()
+	copy(r.d.cells[r.n], bytes.Repeat([]byte{' '}, len(r.d.cells[r.n])))
+	copy(r.d.cells[r.n], text)
+	r.d.mu.Unlock()
+	return r.d.Flush()
+}
+
+// WriteAt writes text starting at col, leaving the rest of the row unchanged.
+func (r Row) WriteAt(col uint8, text string) error {
+	r.d.SetText(col, r.n, text)
+	return r.d.Flush()
+}
+
+// Printf replaces the whole row with formatted text, like Write.
+func (r Row) Printf(format string, args ...interface{}) error {
+	return r.Write(fmt.Sprintf(format, args...))
+}
+
+// Clear blanks the row.
+func (r Row) Clear() error { return r.Write("") }
+
+// Content returns the row's text, including trailing spaces.
+func (r Row) Content() string {
+	r.d.mu.Lock()
+	defer r.d.mu.Unlock()
+	return string(r.d.cells[r.n])
+}
@@ -0,0 +1,65 @@
+package serial_lcd
+
+import (
+	"io"
+	"sync"
+)
+
+// SyncLCD is a connection to an LCD that may be written to from multiple
+// goroutines.  Each write is serialized, and LCD methods that need several
+// writes, such as a cursor move followed by text, hold the lock for all of
+// them.  So the commands and text of concurrent callers are never interleaved:
+// text always lands where its caller moved the cursor.  A Batch holds the lock
+// while it's sent, including any pauses after slow commands.
+//
+// Waiters are woken in a fair order: a sync.Mutex that has waited too long
+// switches to FIFO hand-off, so a busy goroutine can't starve the others.
+type SyncLCD struct {
+	mu  sync.Mutex
+	rwc io.ReadWriteCloser
+}
+
+// Synchronize returns an LCD that is safe to share between goroutines.  It
+// wraps the connection of lcd in a SyncLCD and otherwise behaves just like lcd.
+// Wrap the LCD last, after Tee and the like, so that its methods can find the
+// SyncLCD to hold its lock.
+func Synchronize(lcd LCD) LCD {
+	return LCD{&SyncLCD{rwc: lcd.ReadWriteCloser}, lcd.state(), lcd.vp}
+}
+
+// exclusive calls fn with an LCD whose writes can't be interleaved with those
+// of other goroutines, for methods that need several writes.  If l writes to a
+// SyncLCD, its lock is held while fn runs and fn writes straight to the
+// connection underneath.
+func (l LCD) exclusive(fn func(LCD) error) error {
+	s, ok := l.ReadWriteCloser.(*SyncLCD)
+	if !ok {
+		return fn(l)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l.ReadWriteCloser = s.rwc
+	return fn(l)
+}
+
+func (s *SyncLCD) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rwc.Write(p)
+}
+
+// Read is not serialized so that a blocked read doesn't hold up writers.
+func (s *SyncLCD) Read(p []byte) (int, error) { return s.rwc.Read(p) }
+
+func (s *SyncLCD) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rwc.Close()
+}
+
+func (s *SyncLCD) Drain() error {
+	if d, ok := s.rwc.(drainer); ok {
+		return d.Drain()
+	}
+	return nil
+}
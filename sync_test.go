@@ -0,0 +1,122 @@
+package serial_lcd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// checkRows parses everything sent to the LCD and fails the test unless every
+// character written is rows[r] for the row r that the cursor was last moved
+// to, which is only the case if no other goroutine's writes came between a
+// cursor move and the text that follows it.  Spaces and custom character 0
+// may be written on any row.
+func checkRows(t *testing.T, sent []byte, rows string) {
+	t.Helper()
+	row := -1
+	for i := 0; i < len(sent); {
+		if sent[i] == COMMAND {
+			if i+1 >= len(sent) || sent[i+1] != SET_CURSOR_POSITION || i+3 >= len(sent) {
+				t.Fatalf("unexpected command at %d: %q", i, sent[i:min(i+4, len(sent))])
+			}
+			row = int(sent[i+3]) - 1
+			i += 4
+			continue
+		}
+		if c := sent[i]; c != ' ' && c != 0 && (row < 0 || row >= len(rows) || c != rows[row]) {
+			t.Fatalf("%q written on row %d at %d, near %q", sent[i], row+1, i, sent[max(i-20, 0):min(i+20, len(sent))])
+		}
+		i++
+	}
+}
+
+func TestConcurrentViewports(t *testing.T) {
+	base, port := newMemLCD()
+	lcd := Synchronize(base)
+	lcd.SetSize(20, 4)
+	port.Reset()
+	port.slow = true
+
+	const rows = "abcd"
+	var wg sync.WaitGroup
+	for r := range rows {
+		vp := lcd.Viewport(1, uint8(r)+1, 20, 1)
+		text := strings.Repeat(string(rows[r]), 7) // wraps within the viewport
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if _, err := fmt.Fprint(vp, text); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	checkRows(t, port.Bytes(), rows)
+}
+
+func TestConcurrentHelpers(t *testing.T) {
+	base, port := newMemLCD()
+	lcd := Synchronize(base)
+	lcd.SetSize(20, 4)
+	port.Reset()
+	port.slow = true
+
+	const rows = "abcd"
+	var wg sync.WaitGroup
+	for r := range rows {
+		row, c := uint8(r)+1, rows[r]
+		text := strings.Repeat(string(c), 5)
+		helpers := []func() error{
+			func() error { return lcd.SetLine(row, strings.Repeat(string(c), 20)) },
+			func() error { return lcd.PrintCharAt(3, row, 0) },
+			func() error { return lcd.RightAlign(row, text) },
+			func() error { return lcd.rewrite(1, row, "", text) },
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if err := helpers[i%len(helpers)](); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	checkRows(t, port.Bytes(), rows)
+}
+
+func TestConcurrentDisplays(t *testing.T) {
+	base, port := newMemLCD()
+	lcd := Synchronize(base)
+	lcd.SetSize(20, 4)
+	port.Reset()
+	port.slow = true
+
+	const rows = "abcd"
+	var wg sync.WaitGroup
+	for r := range rows {
+		d, row, c := NewDisplay(lcd), uint8(r), rows[r]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				d.SetText(0, row, strings.Repeat(string(c), 20))
+				d.Flush()
+				d.Clear()
+				d.SetText(uint8(i%20), row, string(c))
+				if err := d.Flush(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	checkRows(t, port.Bytes(), rows)
+}
@@ -0,0 +1,113 @@
+package serial_lcd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tee returns an LCD that sends everything to lcd and also writes a readable
+// description of it to w, such as os.Stderr, for debugging.  Each command is
+// written on its own line as
+//
+//	[15:04:05.000] SET_CURSOR_POSITION 1 2
+//
+// and text is written as
+//
+//	[15:04:05.000] WRITE "Hi there!"
+//
+// with bytes that aren't printable ASCII shown as \xXX.  Errors writing to w
+// are ignored.
+func Tee(lcd LCD, w io.Writer) LCD {
+	return LCD{&tee{ReadWriteCloser: lcd.ReadWriteCloser, lcd: lcd, w: w}, lcd.state(), lcd.vp}
+}
+
+type tee struct {
+	io.ReadWriteCloser
+	lcd LCD
+
+	mu sync.Mutex // keeps lines from different writes apart
+	w  io.Writer
+}
+
+func (t *tee) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	ts := time.Now().Format("15:04:05.000")
+	for _, line := range t.describe(p) {
+		fmt.Fprintf(t.w, "[%s] %s\n", ts, line)
+	}
+	t.mu.Unlock()
+	return t.ReadWriteCloser.Write(p)
+}
+
+func (t *tee) Drain() error {
+	if d, ok := t.ReadWriteCloser.(drainer); ok {
+		return d.Drain()
+	}
+	return nil
+}
+
+// describe splits p into its commands and text and describes each of them.
+func (t *tee) describe(p []byte) []string {
+	var lines []string
+	for len(p) > 0 {
+		if p[0] != COMMAND {
+			n := 0
+			for n < len(p) && p[n] != COMMAND {
+				n++
+			}
+			lines = append(lines, "WRITE "+quoteBytes(p[:n]))
+			p = p[n:]
+			continue
+		}
+		if len(p) < 2 {
+			lines = append(lines, "COMMAND")
+			break
+		}
+		info, ok := commands[p[1]]
+		if !ok {
+			// Without knowing its arguments, assume the rest of p is them.
+			info = commandInfo{name: fmt.Sprintf("COMMAND_0x%02X", p[1]), args: len(p) - 2}
+		}
+		if p[1] == BACKLIGHT_ON && t.lcd.quirks()&QuirkNoBacklightOnArg != 0 {
+			info.args = 0
+		}
+		p = p[2:]
+		n := len(p)
+		if info.args >= 0 {
+			n = min(info.args, n)
+		}
+		line := info.name
+		if info.args < 0 {
+			line += " " + quoteBytes(p[:n])
+		} else {
+			for _, arg := range p[:n] {
+				line += fmt.Sprintf(" %d", arg)
+			}
+		}
+		lines = append(lines, line)
+		p = p[n:]
+	}
+	return lines
+}
+
+// quoteBytes quotes b, showing bytes that aren't printable ASCII as \xXX.
+func quoteBytes(b []byte) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, c := range b {
+		switch {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c < 0x20 || c > 0x7E:
+			fmt.Fprintf(&sb, `\x%02X`, c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
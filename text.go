@@ -0,0 +1,211 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PrintLine writes a line of text read from a file or other line-oriented
+// input.  A leading UTF-8 byte order mark and trailing CR/LF are removed, as
+// are any other control characters, which the LCD would show as garbage.
+// Bytes 0-7 are kept since they display the custom characters.
+func (l LCD) PrintLine(s string) error {
+	s = strings.TrimPrefix(s, "\uFEFF")
+	s = strings.TrimRight(s, "\r\n")
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 && r >= 8 {
+			return -1
+		}
+		return r
+	}, s)
+	return dropN(io.WriteString(l, s))
+}
+
+// InvertedBlockSlot is the custom character slot that SetInverted uses for its
+// full block character.
+const InvertedBlockSlot = 7
+
+// invertedBlockName is the name SetInverted reserves InvertedBlockSlot under.
+const invertedBlockName = "serial_lcd.inverted-block"
+
+// SetInverted turns a simulated negative mode on or off.  The HD44780 can't
+// invert its pixels, so instead text written while inverted has its spaces
+// shown as full blocks and full blocks shown as spaces.  Other characters are
+// unchanged.  Only text written afterwards is affected.
+//
+// Turning it on defines custom character InvertedBlockSlot as a full block and
+// reserves the slot in the LCD's character registry, see RegisterChar, until
+// it's turned off again, after which inverted text still on the screen changes
+// if the slot is reused.  It fails if a registered character is already in
+// that slot.
+func (l LCD) SetInverted(on bool) error {
+	if on {
+		if err := l.reserveChar(invertedBlockName, InvertedBlockSlot, fullBlock); err != nil {
+			return err
+		}
+	} else {
+		l.ReleaseChar(invertedBlockName)
+	}
+	l.state().mu.Lock()
+	l.state().inverted = on
+	l.state().mu.Unlock()
+	return nil
+}
+
+func (l LCD) isInverted() bool {
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	return l.state().inverted
+}
+
+var fullBlock = Char{0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F}
+
+// romFullBlock is the full block character in the HD44780 character ROM.
+const romFullBlock = 0xFF
+
+// invert swaps spaces and full blocks in text for SetInverted.
+func invert(text []byte) []byte {
+	out := make([]byte, len(text))
+	for i, b := range text {
+		switch b {
+		case ' ':
+			out[i] = InvertedBlockSlot
+		case InvertedBlockSlot, romFullBlock:
+			out[i] = ' '
+		default:
+			out[i] = b
+		}
+	}
+	return out
+}
+
+// PrintFit writes as much of s as fits before the end of the cursor's row and
+// drops the rest, rather than letting it wrap onto the next row.
+func (l LCD) PrintFit(s string) error {
+	cols, _ := l.size()
+	col, _ := l.CursorPos()
+	if room := int(cols) - int(col) + 1; len(s) > room {
+		s = s[:max(room, 0)]
+	}
+	return dropN(io.WriteString(l, s))
+}
+
+// RightAlign replaces row (starting at 1) with text placed so that it ends in
+// the last column, such as for a value or a clock in the corner.  Text that is
+// too long loses its beginning rather than its end.  Like SetLine, the rest of
+// the row is blanked unless turned off with SetLineWrapPadding.
+func (l LCD) RightAlign(row uint8, text string) error {
+	cols, _ := l.size()
+	if len(text) > int(cols) {
+		text = text[len(text)-int(cols):]
+	}
+	if l.linePadding() {
+		return l.SetLine(row, strings.Repeat(" ", int(cols)-len(text))+text)
+	}
+	return l.writeAt(cols-uint8(len(text))+1, row, text)
+}
+
+// WriteNumber writes value at col,row right-aligned in a field width
+// characters wide with precision digits after the decimal point, so that the
+// layout doesn't shift as the number changes.  A number too wide for the field
+// is shown as all '#'s rather than overflowing into neighboring text.  A
+// width less than 1 or a negative precision is an error.
+func (l LCD) WriteNumber(col, row uint8, value float64, width, precision int) error {
+	if width < 1 || precision < 0 {
+		return fmt.Errorf("serial_lcd: invalid number format: width %d, precision %d", width, precision)
+	}
+	txt := fmt.Sprintf("%*.*f", width, precision, value)
+	if len(txt) > width {
+		txt = strings.Repeat("#", width)
+	}
+	return l.writeAt(col, row, txt)
+}
+
+// Fill writes c to every cell of the display in a single write, starting from
+// 1,1.  Filling with a block and then clearing makes a simple screen wipe.
+func (l LCD) Fill(c byte) error {
+	cols, rows := l.size()
+	return l.exclusive(func(l LCD) error {
+		if err := l.Home(); err != nil {
+			return err
+		}
+		return dropN(l.Write(bytes.Repeat([]byte{c}, int(cols)*int(rows))))
+	})
+}
+
+// PrintChar writes the custom character in slot (0-7) at the cursor.
+func (l LCD) PrintChar(slot uint8) error {
+	if err := checkSlot(slot); err != nil {
+		return err
+	}
+	return l.WriteBytes([]byte{slot})
+}
+
+// PrintCharAt writes the custom character in slot (0-7) at col,row.
+func (l LCD) PrintCharAt(col, row, slot uint8) error {
+	if err := checkSlot(slot); err != nil {
+		return err
+	}
+	return l.exclusive(func(l LCD) error {
+		if err := l.MoveTo(col, row); err != nil {
+			return err
+		}
+		return l.WriteBytes([]byte{slot})
+	})
+}
+
+func checkSlot(slot uint8) error {
+	if slot >= NumCustomChars {
+		return fmt.Errorf("serial_lcd: invalid custom character slot %d", slot)
+	}
+	return nil
+}
+
+// SetLine replaces row (starting at 1) with text, truncated to the width of the
+// display.  The rest of the row is blanked with spaces, unless that has been
+// turned off with SetLineWrapPadding.
+func (l LCD) SetLine(row uint8, text string) error {
+	cols, _ := l.size()
+	if len(text) > int(cols) {
+		text = text[:cols]
+	}
+	if l.linePadding() {
+		text += strings.Repeat(" ", int(cols)-len(text))
+	}
+	return l.writeAt(1, row, text)
+}
+
+// ClearLine blanks row (starting at 1).
+func (l LCD) ClearLine(row uint8) error {
+	cols, _ := l.size()
+	return l.writeAt(1, row, strings.Repeat(" ", int(cols)))
+}
+
+// writeAt writes text at col,row, without another goroutine's writes coming
+// between the move and the text.
+func (l LCD) writeAt(col, row uint8, text string) error {
+	return l.exclusive(func(l LCD) error {
+		if err := l.MoveTo(col, row); err != nil {
+			return err
+		}
+		return dropN(io.WriteString(l, text))
+	})
+}
+
+// SetLineWrapPadding sets whether SetLine and the helpers built on it write
+// spaces after the text to erase what was left on the row, which is on by
+// default.  Turning it off sends less, which helps when redrawing rapidly, but
+// leaves old characters behind when the new text is shorter.
+func (l LCD) SetLineWrapPadding(pad bool) {
+	l.state().mu.Lock()
+	l.state().noLinePad = !pad
+	l.state().mu.Unlock()
+}
+
+func (l LCD) linePadding() bool {
+	l.state().mu.Lock()
+	defer l.state().mu.Unlock()
+	return !l.state().noLinePad
+}
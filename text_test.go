@@ -0,0 +1,74 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestWriteNumber(t *testing.T) {
+	tests := []struct {
+		value            float64
+		width, precision int
+		want             string
+	}{
+		{3.14159, 6, 2, "  3.14"},
+		{-2, 4, 1, "-2.0"},
+		{12345, 4, 0, "####"},
+		{7, 1, 0, "7"},
+	}
+	for _, test := range tests {
+		lcd, port := newMemLCD()
+		if err := lcd.WriteNumber(1, 1, test.value, test.width, test.precision); err != nil {
+			t.Fatal(err)
+		}
+		want := cat(cmd(SET_CURSOR_POSITION, 1, 1), []byte(test.want))
+		if got := port.Bytes(); !bytes.Equal(got, want) {
+			t.Errorf("WriteNumber(%v, %d, %d) sent %q, want %q", test.value, test.width, test.precision, got, want)
+		}
+	}
+
+	lcd, port := newMemLCD()
+	if err := lcd.WriteNumber(1, 1, 12345, -3, 0); err == nil {
+		t.Error("WriteNumber accepted a negative width")
+	}
+	if err := lcd.WriteNumber(1, 1, 7, 0, 0); err == nil {
+		t.Error("WriteNumber accepted a zero width")
+	}
+	if err := lcd.WriteNumber(1, 1, 1, 4, -1); err == nil {
+		t.Error("WriteNumber accepted a negative precision")
+	}
+	if got := port.Bytes(); len(got) != 0 {
+		t.Errorf("WriteNumber with an invalid format sent %q", got)
+	}
+}
+
+func TestSetInverted_ReservesSlot(t *testing.T) {
+	lcd, _ := newMemLCD()
+	if err := lcd.SetInverted(true); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < NumCustomChars-1; i++ {
+		slot, err := lcd.RegisterChar(fmt.Sprint("char", i), Char{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slot == InvertedBlockSlot {
+			t.Fatalf("RegisterChar handed out slot %d while it's inverted", slot)
+		}
+	}
+	if _, err := lcd.RegisterChar("one too many", Char{}); err != ErrNoFreeSlots {
+		t.Errorf("RegisterChar with every slot taken returned %v, want ErrNoFreeSlots", err)
+	}
+
+	// Turning it off frees the slot.
+	if err := lcd.SetInverted(false); err != nil {
+		t.Fatal(err)
+	}
+	if slot, err := lcd.RegisterChar("last", Char{}); err != nil || slot != InvertedBlockSlot {
+		t.Errorf("RegisterChar after SetInverted(false) = %d, %v, want slot %d", slot, err, InvertedBlockSlot)
+	}
+	if err := lcd.SetInverted(true); err == nil {
+		t.Error("SetInverted(true) succeeded with its slot taken")
+	}
+}
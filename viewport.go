@@ -0,0 +1,120 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Viewport returns an LCD that draws only within the width by height rectangle
+// whose top left corner is at col,row (starting at 1,1), so that several
+// widgets can share a larger display without adjusting their coordinates.
+//
+// Positions given to the viewport, such as to MoveTo, are relative to its top
+// left corner, and helpers that fit their output to the display fit it to the
+// viewport instead.  Home moves to the viewport's corner, Clear blanks only the
+// viewport's cells, and text written to it wraps at its edges.  The viewport is
+// limited to the part of the rectangle that's on the display.
+//
+// Each viewport keeps track of its own cursor, and every write to it is sent
+// together with a move to that cursor as a single write.  So once the LCD has
+// been wrapped with Synchronize, separate goroutines can each draw in their own
+// viewport at the same time without their text landing in the wrong place.
+// Create the viewports from the synchronized LCD so that they share its lock.
+// A single viewport is not meant to be used by several goroutines at once.
+func (l LCD) Viewport(col, row, width, height uint8) LCD {
+	cols, rows := l.size()
+	col, row = max(col, 1), max(row, 1)
+	width = uint8(max(min(int(width), int(cols)-int(col)+1), 0))
+	height = uint8(max(min(int(height), int(rows)-int(row)+1), 0))
+	if l.vp != nil {
+		col, row = col+l.vp.col-1, row+l.vp.row-1
+	}
+	l.vp = &viewport{col: col, row: row, cols: width, rows: height, curCol: 1, curRow: 1}
+	return l
+}
+
+// viewport is the part of the display that a Viewport draws in.
+type viewport struct {
+	col, row   uint8 // top left corner on the display, starting at 1,1
+	cols, rows uint8
+
+	mu             sync.Mutex
+	curCol, curRow uint8 // cursor position in the viewport, starting at 1,1
+}
+
+func (v *viewport) cursor() (col, row uint8) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.curCol, v.curRow
+}
+
+func (v *viewport) setCursor(col, row uint8) {
+	v.mu.Lock()
+	v.curCol, v.curRow = col, row
+	v.mu.Unlock()
+}
+
+// screen returns l without the viewport, for sending positions that have
+// already been translated to the display.
+func screen(l LCD) LCD { return LCD{l.ReadWriteCloser, l.state(), nil} }
+
+func (v *viewport) moveTo(l LCD, col, row uint8) error {
+	if err := screen(l).MoveTo(col+v.col-1, row+v.row-1); err != nil {
+		return err
+	}
+	v.setCursor(col, row)
+	return nil
+}
+
+// write writes text at the viewport's cursor, wrapping it at the edges of the
+// viewport.  The text is sent in one write that starts by moving the LCD's
+// cursor to the viewport's, in case something else has moved it.
+func (v *viewport) write(l LCD, p []byte) (int, error) {
+	if v.cols == 0 || v.rows == 0 || len(p) == 0 {
+		return len(p), nil
+	}
+	col, row := v.cursor()
+	if col < 1 || col > v.cols+1 || row < 1 || row > v.rows {
+		col, row = 1, 1
+	}
+	n := 0
+	err := l.Batch(func(b LCD) error {
+		b = screen(b)
+		for first := true; len(p) > 0; first = false {
+			if col > v.cols {
+				col, row = 1, row%v.rows+1
+			}
+			if first || col == 1 {
+				if err := b.MoveTo(col+v.col-1, row+v.row-1); err != nil {
+					return err
+				}
+			}
+			k := min(len(p), int(v.cols-col+1))
+			if err := dropN(b.write(p[:k])); err != nil {
+				return err
+			}
+			n, p, col = n+k, p[k:], col+uint8(k)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	v.setCursor(col, row)
+	return n, nil
+}
+
+// clear blanks the viewport and moves the cursor to its top left corner.
+func (v *viewport) clear(l LCD) error {
+	return l.Batch(func(l LCD) error {
+		for r := uint8(1); r <= v.rows; r++ {
+			if err := l.MoveTo(1, r); err != nil {
+				return err
+			}
+			if err := dropN(screen(l).write(bytes.Repeat([]byte{' '}, int(v.cols)))); err != nil {
+				return err
+			}
+		}
+		return l.MoveTo(1, 1)
+	})
+}
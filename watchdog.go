@@ -0,0 +1,88 @@
+package serial_lcd
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Watchdog turns off an LCD's backlight if it isn't kicked regularly, so that
+// a display whose program has hung goes dark instead of showing stale and
+// possibly misleading information.  Call Kick whenever the display is
+// updated.
+type Watchdog struct {
+	lcd     LCD
+	timeout time.Duration
+	kicks   chan struct{}
+
+	mu      sync.Mutex
+	started bool
+	blanked bool
+}
+
+// NewWatchdog returns a Watchdog that blanks lcd when timeout passes without a
+// Kick.  It doesn't run until Start is called.
+func NewWatchdog(lcd LCD, timeout time.Duration) *Watchdog {
+	return &Watchdog{lcd: lcd, timeout: timeout, kicks: make(chan struct{}, 1)}
+}
+
+// Start runs the watchdog in a new goroutine until ctx is cancelled.  A
+// watchdog only runs once: calling Start again does nothing, even after ctx is
+// cancelled.  Failing to turn off the backlight is logged with WithLogger.
+func (w *Watchdog) Start(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started {
+		return
+	}
+	w.started = true
+	go func() {
+		timer := time.NewTimer(w.timeout)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.kicks:
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(w.timeout)
+			case <-timer.C:
+				w.mu.Lock()
+				if !w.blanked {
+					w.blanked = true
+					if err := w.lcd.Off(); err != nil {
+						w.lcd.logf("serial_lcd: watchdog: %v", err)
+					}
+				}
+				w.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Kick restarts the timeout.  Once the display has been blanked, kicking it
+// doesn't turn it back on; use Reset for that.
+func (w *Watchdog) Kick() {
+	select {
+	case w.kicks <- struct{}{}:
+	default: // a kick is already pending
+	}
+}
+
+// Reset turns the backlight back on after the watchdog blanked the display and
+// restarts the timeout.
+func (w *Watchdog) Reset() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Kick()
+	if !w.blanked {
+		return nil
+	}
+	w.blanked = false
+	return w.lcd.On()
+}
@@ -0,0 +1,47 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer that's safe to log to from other goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchdog_LogsFailedOff(t *testing.T) {
+	lcd := New(&failingPort{err: syscall.EIO})
+	var logs syncBuffer
+	lcd.state().logger = log.New(&logs, "", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := NewWatchdog(lcd, 10*time.Millisecond)
+	w.Start(ctx)
+	w.Start(ctx) // does nothing
+	time.Sleep(50 * time.Millisecond)
+
+	if n := strings.Count(logs.String(), "watchdog"); n != 1 {
+		t.Errorf("logged %d watchdog failures, want 1:\n%s", n, logs.String())
+	}
+}
@@ -0,0 +1,473 @@
+package serial_lcd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// CountdownFlashes is how many times Countdown flashes the backlight when it
+// reaches zero.  Set it to 0 to disable flashing.
+var CountdownFlashes = 3
+
+// Countdown displays the time remaining in d as MM:SS in the top left corner,
+// updating it every second, and calls onDone (if not nil) once it reaches zero.
+// Only the digits that change are rewritten.  It blocks until the countdown
+// finishes, returning ctx.Err() if ctx is cancelled first.
+func (l LCD) Countdown(ctx context.Context, d time.Duration, onDone func()) error {
+	deadline := time.Now().Add(d)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var shown string
+	for {
+		left := time.Until(deadline).Round(time.Second)
+		if left < 0 {
+			left = 0
+		}
+		txt := fmt.Sprintf("%02d:%02d", int(left/time.Minute), int(left%time.Minute/time.Second))
+		if err := l.rewrite(1, 1, shown, txt); err != nil {
+			return err
+		}
+		shown = txt
+		if left == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	if onDone != nil {
+		onDone()
+	}
+	return l.FlashContext(ctx, CountdownFlashes, 500*time.Millisecond)
+}
+
+// rewrite updates text previously written at col,row from old to new, only
+// sending the runs of characters that differ.
+func (l LCD) rewrite(col, row uint8, old, new string) error {
+	for i := 0; i < len(new); {
+		if i < len(old) && old[i] == new[i] {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(new) && (j >= len(old) || old[j] != new[j]) {
+			j++
+		}
+		if err := l.writeAt(col+uint8(i), row, new[i:j]); err != nil {
+			return err
+		}
+		i = j
+	}
+	return nil
+}
+
+// Custom character slots used by DrawGauge.
+const (
+	GaugeTrackSlot  = 5
+	GaugeNeedleSlot = 6
+)
+
+// Names that DrawGauge reserves its slots under.
+const (
+	gaugeTrackName  = "serial_lcd.gauge-track"
+	gaugeNeedleName = "serial_lcd.gauge-needle"
+)
+
+// DrawGauge draws a horizontal gauge widthCells characters wide starting at
+// startCol,row, with a needle at the given fraction (0-1) of the way across
+// and a filled track behind it.  The needle moves a pixel at a time, so a
+// gauge has 5 positions per character.  It defines custom characters
+// GaugeTrackSlot and GaugeNeedleSlot and reserves them in the LCD's character
+// registry, see RegisterChar, since the gauge keeps showing them.  It fails if
+// a registered character is already in either slot.
+func (l LCD) DrawGauge(startCol, row uint8, fraction float64, widthCells uint8) error {
+	if widthCells == 0 {
+		return nil
+	}
+	pos := int(math.Round(math.Max(0, math.Min(fraction, 1)) * float64(int(widthCells)*5-1)))
+	needle := Char{}
+	for y := range needle {
+		needle[y] = 0x10 >> uint(pos%5)
+		if y == 3 || y == 4 {
+			needle[y] |= 0x1F &^ (0x0F >> uint(pos%5))
+		}
+	}
+	track := Char{0, 0, 0, 0x1F, 0x1F, 0, 0, 0}
+	if err := l.reserveChar(gaugeTrackName, GaugeTrackSlot, track); err != nil {
+		return err
+	}
+	if err := l.reserveChar(gaugeNeedleName, GaugeNeedleSlot, needle); err != nil {
+		return err
+	}
+	cells := make([]byte, widthCells)
+	for i := range cells {
+		switch {
+		case i < pos/5:
+			cells[i] = GaugeTrackSlot
+		case i == pos/5:
+			cells[i] = GaugeNeedleSlot
+		default:
+			cells[i] = ' '
+		}
+	}
+	return l.exclusive(func(l LCD) error {
+		if err := l.MoveTo(startCol, row); err != nil {
+			return err
+		}
+		return l.WriteBytes(cells)
+	})
+}
+
+// testHeartName is the name TestPattern registers its heart under.
+const testHeartName = "serial_lcd.test-heart"
+
+// testPatternPause is how long TestPattern shows each step for.
+var testPatternPause = 500 * time.Millisecond
+
+// TestPattern runs through the display's features so that the wiring and
+// settings of a new display can be checked by eye.  It cycles the backlight
+// through red, green, blue and white, fills every cell, shows each cursor mode
+// and finally draws a heart.  It takes a few seconds.
+//
+// The backlight color is restored afterwards, even if a step fails, but each
+// color is saved to the EEPROM, see SetBG, so that's five writes per run.  The
+// heart is added with RegisterChar, so it doesn't clobber any registered
+// character, and its slot is released again at the end.
+func (l LCD) TestPattern() (err error) {
+	bg := l.Snapshot().BG
+	defer func() {
+		l.ReleaseChar(testHeartName)
+		if e := l.SetBG(bg.R, bg.G, bg.B); err == nil {
+			err = e
+		}
+	}()
+	steps := []func(l LCD) error{
+		func(l LCD) error { return l.SetBG(255, 0, 0) },
+		func(l LCD) error { return l.SetBG(0, 255, 0) },
+		func(l LCD) error { return l.SetBG(0, 0, 255) },
+		func(l LCD) error { return l.SetBG(255, 255, 255) },
+		func(l LCD) error {
+			cols, rows := l.size()
+			pattern := make([]byte, int(cols)*int(rows))
+			for i := range pattern {
+				pattern[i] = '0' + byte(i%10)
+			}
+			if err := l.Home(); err != nil {
+				return err
+			}
+			return l.WriteBytes(pattern)
+		},
+		func(l LCD) error {
+			if err := l.Clear(); err != nil {
+				return err
+			}
+			return dropN(io.WriteString(l, "underline:"))
+		},
+		func(l LCD) error { return l.SetCursor(UNDERLINE_CURSOR_ON, BLOCK_CURSOR_OFF) },
+		func(l LCD) error {
+			if err := l.SetCursor(UNDERLINE_CURSOR_OFF, BLOCK_CURSOR_OFF); err != nil {
+				return err
+			}
+			if err := l.Clear(); err != nil {
+				return err
+			}
+			return dropN(io.WriteString(l, "block:"))
+		},
+		func(l LCD) error { return l.SetCursor(UNDERLINE_CURSOR_OFF, BLOCK_CURSOR_ON) },
+		func(l LCD) error {
+			if err := l.SetCursor(UNDERLINE_CURSOR_OFF, BLOCK_CURSOR_OFF); err != nil {
+				return err
+			}
+			heart := MakeChar([8]string{
+				".....",
+				".*.*.",
+				"*.*.*",
+				"*...*",
+				"*...*",
+				".*.*.",
+				"..*..",
+				".....",
+			})
+			slot, err := l.RegisterChar(testHeartName, heart)
+			if err != nil {
+				return err
+			}
+			if err := l.Clear(); err != nil {
+				return err
+			}
+			if err := dropN(io.WriteString(l, "Test done ")); err != nil {
+				return err
+			}
+			return l.PrintChar(slot)
+		},
+	}
+	for _, step := range steps {
+		if err := l.exclusive(step); err != nil {
+			return err
+		}
+		time.Sleep(testPatternPause)
+	}
+	return nil
+}
+
+// SoftwareBlinkCursor blinks a block cursor at the cursor position by turning
+// it on and off every interval, for when the hardware blink rate (which the
+// firmware has no command to change) is too fast or too slow.  It blocks until
+// ctx is cancelled, then leaves the cursors off and returns ctx.Err().
+func (l LCD) SoftwareBlinkCursor(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for on := true; ; on = !on {
+		block := BLOCK_CURSOR_OFF
+		if on {
+			block = BLOCK_CURSOR_ON
+		}
+		if err := l.SetCursor(UNDERLINE_CURSOR_OFF, block); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			if err := l.SetCursor(UNDERLINE_CURSOR_OFF, BLOCK_CURSOR_OFF); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// WipeDirection is the direction that WipeTo reveals a new screen in.
+type WipeDirection int
+
+const (
+	WipeRight WipeDirection = iota // reveal from the left edge to the right
+	WipeLeft                       // reveal from the right edge to the left
+)
+
+// WipeTo replaces the display's content with frame one column at a time,
+// sleeping perColumn between columns, so the new screen wipes in instead of
+// appearing all at once.  frame is indexed [row][col] with rows and columns
+// starting at 0; cells it doesn't cover are blanked.  Runes above 255 can't
+// be displayed and are shown as '?'.
+func (l LCD) WipeTo(frame [][]rune, direction WipeDirection, perColumn time.Duration) error {
+	cols, rows := l.size()
+	for i := 0; i < int(cols); i++ {
+		col := i
+		if direction == WipeLeft {
+			col = int(cols) - 1 - i
+		}
+		err := l.Batch(func(b LCD) error {
+			for row := 0; row < int(rows); row++ {
+				c := byte(' ')
+				if row < len(frame) && col < len(frame[row]) {
+					c = runeByte(frame[row][col])
+				}
+				if err := b.MoveTo(uint8(col)+1, uint8(row)+1); err != nil {
+					return err
+				}
+				if err := dropN(b.Write([]byte{c})); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if i < int(cols)-1 {
+			time.Sleep(perColumn)
+		}
+	}
+	return nil
+}
+
+// runeByte converts r to the character byte sent to the LCD.
+func runeByte(r rune) byte {
+	if r < 0 || r > 0xFF {
+		return '?'
+	}
+	return byte(r)
+}
+
+// Marquee scrolls text across a row, moving it one character to the left every
+// interval, until ctx is cancelled, and then returns ctx.Err().  The text repeats with a gap of spaces
+// after it.  The backpack's autoscroll has no adjustable speed, so Marquee is
+// the way to run a ticker at a chosen rate.  Only the characters that change
+// are sent each step.
+//
+// For example, to scroll a headline across the second row for a minute:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	lcd.Marquee(ctx, 2, "Breaking news: gophers love LCDs", 300*time.Millisecond)
+func (l LCD) Marquee(ctx context.Context, row uint8, text string, interval time.Duration) error {
+	cols, _ := l.size()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var shown string
+	for offset := 0; ; offset++ {
+		view := scrollWindow(text, offset, int(cols))
+		if err := l.rewrite(1, row, shown, view); err != nil {
+			return err
+		}
+		shown = view
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ScrollRowOnce draws one frame of a ticker on row (starting at 1): text
+// scrolled step characters to the left, looping with a gap of spaces like
+// Marquee.  The whole row is redrawn and the other rows are left alone, so
+// calling it with step increasing by one for each frame animates the row at
+// whatever rate the caller chooses.  A negative step scrolls to the right.
+func (l LCD) ScrollRowOnce(row uint8, text string, step int) error {
+	cols, _ := l.size()
+	return l.SetLine(row, scrollWindow(text, step, int(cols)))
+}
+
+// MultiMarquee runs a marquee on each of the first rows of the display, like a
+// departures board: texts[i] scrolls across row i+1, moving one character every
+// intervals[i].  It runs until ctx is cancelled, and then returns ctx.Err().
+// The rows that move together
+// are updated in a single write, and only the characters that change are sent.
+func (l LCD) MultiMarquee(ctx context.Context, texts []string, intervals []time.Duration) error {
+	if len(texts) != len(intervals) {
+		return fmt.Errorf("serial_lcd: %d marquee texts but %d intervals", len(texts), len(intervals))
+	}
+	for _, iv := range intervals {
+		if iv <= 0 {
+			return fmt.Errorf("serial_lcd: invalid marquee interval %v", iv)
+		}
+	}
+	cols, rows := l.size()
+	n := min(len(texts), int(rows))
+	if n == 0 {
+		return nil
+	}
+	offsets, shown := make([]int, n), make([]string, n)
+	next := make([]time.Time, n)
+	for i := range next {
+		next[i] = time.Now()
+	}
+	for {
+		now := time.Now()
+		err := l.Batch(func(b LCD) error {
+			for i := range next {
+				if now.Before(next[i]) {
+					continue
+				}
+				view := scrollWindow(texts[i], offsets[i], int(cols))
+				if err := b.rewrite(1, uint8(i)+1, shown[i], view); err != nil {
+					return err
+				}
+				shown[i] = view
+				offsets[i]++
+				next[i] = next[i].Add(intervals[i])
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		soonest := next[0]
+		for _, t := range next[1:] {
+			if t.Before(soonest) {
+				soonest = t
+			}
+		}
+		if err := sleep(ctx, time.Until(soonest)); err != nil {
+			return err
+		}
+	}
+}
+
+// marqueeGap is the least number of spaces between repeats of scrolling text.
+const marqueeGap = 4
+
+// scrollWindow returns the width characters of a looping ticker of text that
+// are visible after scrolling offset characters, which may be negative.
+func scrollWindow(text string, offset, width int) string {
+	loop := text + strings.Repeat(" ", max(marqueeGap, width-len(text)))
+	offset %= len(loop)
+	if offset < 0 {
+		offset += len(loop)
+	}
+	view := make([]byte, width)
+	for i := range view {
+		view[i] = loop[(offset+i)%len(loop)]
+	}
+	return string(view)
+}
+
+// DrawMenu draws a list of menu items, one per row, starting with item
+// topVisible on the first row.  The selected item is marked with a '>' and
+// items too long for the display are truncated.  Scroll the menu by changing
+// topVisible to keep the selected item on screen.
+func (l LCD) DrawMenu(items []string, selected int, topVisible int) error {
+	_, rows := l.size()
+	return l.Batch(func(b LCD) error {
+		for r := 0; r < int(rows); r++ {
+			line, i := "", topVisible+r
+			if i >= 0 && i < len(items) {
+				marker := " "
+				if i == selected {
+					marker = ">"
+				}
+				line = marker + items[i]
+			}
+			if err := b.SetLine(uint8(r)+1, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// minSweepStep is the smallest step SweepContrast takes.
+const minSweepStep = 16
+
+// SweepContrast steps the contrast from 0 to 255 in increments of step,
+// showing each value for dwell along with some sample text, so that the best
+// value for a display can be found by eye.  Afterwards the contrast is
+// restored.  This helps when the display is unreadable because the contrast is
+// wrong out of the box.
+//
+// The backpack saves the contrast to its EEPROM every time, and EEPROM wears
+// out after around 100,000 writes, so steps smaller than 16 are raised to 16.
+// A sweep then makes 16 writes, plus one to restore the contrast; run it when
+// setting up a display, not routinely.
+func (l LCD) SweepContrast(step uint8, dwell time.Duration) error {
+	orig := l.Contrast()
+	cols, rows := l.size()
+	const chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	sample := strings.Repeat(chars, int(cols)/len(chars)+1)[:cols]
+	for c := 0; c <= 255; c += int(max(step, minSweepStep)) {
+		err := l.Batch(func(b LCD) error {
+			if err := b.SetContrast(uint8(c)); err != nil {
+				return err
+			}
+			if err := b.SetLine(1, fmt.Sprintf("Contrast: %d", c)); err != nil {
+				return err
+			}
+			if rows < 2 {
+				return nil
+			}
+			return b.SetLine(2, sample)
+		})
+		if err != nil {
+			return err
+		}
+		time.Sleep(dwell)
+	}
+	return l.SetContrast(orig)
+}
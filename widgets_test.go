@@ -0,0 +1,126 @@
+package serial_lcd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDrawGauge(t *testing.T) {
+	lcd, port := newMemLCD()
+	if err := lcd.DrawGauge(1, 2, 0.5, 4); err != nil {
+		t.Fatal(err)
+	}
+	// Positions 0-19, so 0.5 rounds to 10, the first pixel of the third cell.
+	want := cat(
+		cmd(CREATE_CUSTOM_CHARACTER, GaugeTrackSlot, 0, 0, 0, 0x1F, 0x1F, 0, 0, 0),
+		cmd(CREATE_CUSTOM_CHARACTER, GaugeNeedleSlot, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10),
+		cmd(SET_CURSOR_POSITION, 1, 2), []byte{GaugeTrackSlot, GaugeTrackSlot, GaugeNeedleSlot, ' '},
+	)
+	if got := port.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("DrawGauge sent %q, want %q", got, want)
+	}
+}
+
+func TestDrawGauge_ReservesSlots(t *testing.T) {
+	lcd, _ := newMemLCD()
+	if err := lcd.DrawGauge(1, 1, 0.3, 8); err != nil {
+		t.Fatal(err)
+	}
+	for lcd.FreeCharSlots() > 0 {
+		slot, err := lcd.RegisterChar(string(rune('a'+lcd.FreeCharSlots())), Char{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slot == GaugeTrackSlot || slot == GaugeNeedleSlot {
+			t.Fatalf("RegisterChar handed out gauge slot %d", slot)
+		}
+	}
+	// Redrawing keeps using the same slots.
+	if err := lcd.DrawGauge(1, 1, 0.7, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := newMemLCD()
+	for i := 0; i < NumCustomChars; i++ {
+		other.RegisterChar(string(rune('a'+i)), Char{})
+	}
+	if err := other.DrawGauge(1, 1, 0.5, 8); err == nil {
+		t.Error("DrawGauge succeeded with its slots taken")
+	}
+}
+
+func TestTestPattern(t *testing.T) {
+	orig, origBG := testPatternPause, MinBGInterval
+	testPatternPause, MinBGInterval = 0, 0
+	defer func() { testPatternPause, MinBGInterval = orig, origBG }()
+
+	lcd, port := newMemLCD()
+	lcd.SetBG(1, 2, 3)
+	slot, err := lcd.RegisterChar("mine", Char{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lcd.TestPattern(); err != nil {
+		t.Fatal(err)
+	}
+	if bg := lcd.Snapshot().BG; bg != (Color{1, 2, 3}) {
+		t.Errorf("BG after TestPattern is %v, want it restored to rgb(1,2,3)", bg)
+	}
+	sent := port.Bytes()
+	if !bytes.HasSuffix(sent, cmd(SET_RGB_BACKLIGHT_COLOR, 1, 2, 3)) {
+		t.Errorf("TestPattern didn't end by restoring the BG: %q", sent[max(len(sent)-20, 0):])
+	}
+	if n := bytes.Count(sent, []byte{COMMAND, CREATE_CUSTOM_CHARACTER, slot}); n != 1 {
+		t.Errorf("TestPattern overwrote registered slot %d", slot)
+	}
+	if n := lcd.FreeCharSlots(); n != NumCustomChars-1 {
+		t.Errorf("%d free slots after TestPattern, want %d", n, NumCustomChars-1)
+	}
+}
+
+func TestSweepContrast(t *testing.T) {
+	lcd, port := newMemLCD()
+	if err := lcd.SweepContrast(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	sent := port.Bytes()
+	if n, want := bytes.Count(sent, cmd(CONTRAST)), 256/minSweepStep+1; n != want {
+		t.Errorf("sweep sent %d contrast changes, want %d", n, want)
+	}
+	if !bytes.HasSuffix(sent, cmd(CONTRAST, 200)) {
+		t.Error("sweep didn't restore the contrast")
+	}
+	if !bytes.Contains(sent, cat(cmd(SET_CURSOR_POSITION, 1, 2), []byte("0123456789ABCDEF"), cmd(CONTRAST))) {
+		t.Error("sample text doesn't fill the 16 column row")
+	}
+}
+
+func TestMarquee_ReturnsCtxErr(t *testing.T) {
+	lcd, _ := newMemLCD()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := lcd.Marquee(ctx, 1, "a long line of text to scroll", time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("Marquee returned %v after its deadline, want context.DeadlineExceeded", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	err := lcd.MultiMarquee(ctx, []string{"one", "two"}, []time.Duration{time.Millisecond, time.Millisecond})
+	if err != context.Canceled {
+		t.Errorf("MultiMarquee returned %v after cancel, want context.Canceled", err)
+	}
+}
+
+func TestSoftwareBlinkCursor_ReturnsCtxErr(t *testing.T) {
+	lcd, port := newMemLCD()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := lcd.SoftwareBlinkCursor(ctx, time.Millisecond); err != context.Canceled {
+		t.Errorf("SoftwareBlinkCursor returned %v after cancel, want context.Canceled", err)
+	}
+	if !bytes.HasSuffix(port.Bytes(), cat(cmd(byte(UNDERLINE_CURSOR_OFF)), cmd(byte(BLOCK_CURSOR_OFF)))) {
+		t.Errorf("SoftwareBlinkCursor left the cursor on: sent %q", port.Bytes())
+	}
+}
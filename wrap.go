@@ -0,0 +1,114 @@
+package serial_lcd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// NopOnError returns an LCD that silently discards writes that fail because
+// the display is gone, such as when it has been unplugged or closed, and
+// returns nil from the method instead of the error.  This suits programs where
+// the display is optional and checking errors at every call would be noise.
+// Other errors, such as for invalid arguments, are returned as usual, as are
+// errors from Close and from queries such as Version.  Ping and IsConnected
+// still report whether the display can be reached.
+func NopOnError(lcd LCD) LCD {
+	return LCD{nopOnError{lcd.ReadWriteCloser}, lcd.state(), lcd.vp}
+}
+
+// NullLCD returns an LCD that discards everything sent to it, like io.Discard,
+// for running without a display attached.  Its methods return nil, even after
+// it's closed, except for queries such as Version, which get no reply.
+//
+// For example, to make the display optional:
+//
+//	lcd := serial_lcd.NullLCD()
+//	if *port != "" {
+//		if lcd, err = serial_lcd.Open(*port, 9600); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+func NullLCD() LCD { return LCD{discard{}, newState(), nil} }
+
+// discard is a connection that drops everything written to it.
+type discard struct{}
+
+func (discard) Read(p []byte) (int, error)  { return 0, io.EOF }
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+func (discard) Close() error                { return nil }
+
+type nopOnError struct{ io.ReadWriteCloser }
+
+func (n nopOnError) Write(p []byte) (int, error) {
+	if k, err := n.ReadWriteCloser.Write(p); err != nil && !isDisconnect(err) {
+		return k, err
+	}
+	return len(p), nil
+}
+
+// isDisconnect reports whether err means that the display can't be reached,
+// such as after it's closed or unplugged.
+func isDisconnect(err error) bool {
+	for _, target := range []error{ErrClosed, os.ErrClosed, io.ErrClosedPipe, io.EOF,
+		syscall.EIO, syscall.ENXIO, syscall.ENODEV, syscall.EPIPE} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n nopOnError) Drain() error {
+	if d, ok := n.ReadWriteCloser.(drainer); ok {
+		if err := d.Drain(); err != nil && !isDisconnect(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// mirrorQueueSize is how many writes a mirror's secondary LCD can fall behind
+// by before they are combined.
+const mirrorQueueSize = 64
+
+// Mirror returns an LCD that sends everything to both primary and secondary,
+// such as for a sign that can be seen from both sides.  Writes to secondary
+// are queued and sent in the background so that a slow secondary doesn't hold
+// up the primary.  Errors from primary are returned as usual, while errors from
+// secondary are only logged to secondary's logger.  The returned LCD tracks
+// state, such as the cursor position, like primary.  Closing it closes both.
+func Mirror(primary, secondary LCD) LCD {
+	return LCD{mirror{primary.ReadWriteCloser, secondary, secondary.Async(mirrorQueueSize).q}, primary.state(), primary.vp}
+}
+
+type mirror struct {
+	io.ReadWriteCloser
+	secondary LCD
+	q         *asyncQueue
+}
+
+func (m mirror) Write(p []byte) (int, error) {
+	if _, err := m.q.Write(p); err != nil {
+		m.secondary.logf("serial_lcd: mirror: %v", err)
+	}
+	return m.ReadWriteCloser.Write(p)
+}
+
+func (m mirror) Drain() error {
+	if err := m.q.Drain(); err != nil {
+		m.secondary.logf("serial_lcd: mirror: %v", err)
+	}
+	if d, ok := m.ReadWriteCloser.(drainer); ok {
+		return d.Drain()
+	}
+	return nil
+}
+
+func (m mirror) Close() error {
+	if err := m.q.Close(); err != nil && err != ErrClosed {
+		m.secondary.logf("serial_lcd: mirror: %v", err)
+	}
+	return m.ReadWriteCloser.Close()
+}
@@ -0,0 +1,48 @@
+package serial_lcd
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+// failingPort is a serial port whose writes all fail with err.
+type failingPort struct {
+	memPort
+	err error
+}
+
+func (p *failingPort) Write([]byte) (int, error) { return 0, p.err }
+
+func TestNopOnError(t *testing.T) {
+	unplugged := NopOnError(New(&failingPort{err: syscall.EIO}))
+	if err := unplugged.Clear(); err != nil {
+		t.Errorf("Clear on an unplugged LCD returned %v", err)
+	}
+	if unplugged.IsConnected() {
+		t.Error("IsConnected is true for an unplugged LCD")
+	}
+	if err := Synchronize(unplugged).Ping(); err == nil {
+		t.Error("Ping succeeded on a synchronized unplugged LCD")
+	}
+
+	closed := NopOnError(New(&memPort{}))
+	closed.Close()
+	if err := closed.Clear(); err != nil {
+		t.Errorf("Clear on a closed LCD returned %v", err)
+	}
+	if closed.IsConnected() {
+		t.Error("IsConnected is true for a closed LCD")
+	}
+
+	parity := errors.New("parity error")
+	if err := NopOnError(New(&failingPort{err: parity})).Clear(); err != parity {
+		t.Errorf("Clear returned %v, want the parity error", err)
+	}
+	if err := NopOnError(NullLCD()).PrintChar(8); err == nil {
+		t.Error("PrintChar accepted an invalid slot")
+	}
+	if lcd := NopOnError(NullLCD()); !lcd.IsConnected() {
+		t.Error("IsConnected is false for a working LCD")
+	}
+}